@@ -0,0 +1,173 @@
+package analyticstest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Error is an error returned by the server in the errors field of a response.
+type Error struct {
+	// Code is the error code.
+	Code uint32
+
+	// Message is the error message.
+	Message string
+
+	// Retriable indicates whether clients may retry the request.
+	Retriable bool
+}
+
+// Response describes how the server responds to a query.
+//
+// Responses are created using Rows, Errors or HTTPStatus and can be adjusted further using the Set methods.
+type Response struct {
+	httpStatus int
+	rows       []interface{}
+	errors     []Error
+	plan       interface{}
+
+	delay           time.Duration
+	rowDelay        time.Duration
+	disconnectAfter int
+	pendingPolls    int
+}
+
+// Rows creates a successful response containing the rows. Each row is encoded as JSON.
+func Rows(rows ...interface{}) *Response {
+	return &Response{
+		httpStatus:      http.StatusOK,
+		rows:            rows,
+		errors:          nil,
+		plan:            nil,
+		delay:           0,
+		rowDelay:        0,
+		disconnectAfter: -1,
+		pendingPolls:    0,
+	}
+}
+
+// Errors creates a failed response containing the errors.
+func Errors(errs ...Error) *Response {
+	return &Response{
+		httpStatus:      http.StatusOK,
+		rows:            nil,
+		errors:          errs,
+		plan:            nil,
+		delay:           0,
+		rowDelay:        0,
+		disconnectAfter: -1,
+		pendingPolls:    0,
+	}
+}
+
+// HTTPStatus creates a response with the HTTP status code and an empty JSON object as the body, containing no
+// rows or errors.
+func HTTPStatus(code int) *Response {
+	return &Response{
+		httpStatus:      code,
+		rows:            nil,
+		errors:          nil,
+		plan:            nil,
+		delay:           0,
+		rowDelay:        0,
+		disconnectAfter: -1,
+		pendingPolls:    0,
+	}
+}
+
+// SetHTTPStatus sets the HTTP status code of the response, such as 503 for a response containing errors.
+func (r *Response) SetHTTPStatus(code int) *Response {
+	r.httpStatus = code
+
+	return r
+}
+
+// SetErrors sets errors which the response fails with once its rows have been sent, as when a query fails part
+// way through producing its results.
+func (r *Response) SetErrors(errs ...Error) *Response {
+	r.errors = errs
+
+	return r
+}
+
+// SetPlan sets the plan sent alongside the rows when the request asks for its logical or optimized logical plan.
+// The plan is encoded as JSON, or as a JSON string if the request asks for plans in string format.
+func (r *Response) SetPlan(plan interface{}) *Response {
+	r.plan = plan
+
+	return r
+}
+
+// SetDelay sets how long the server waits before sending the response headers.
+func (r *Response) SetDelay(delay time.Duration) *Response {
+	r.delay = delay
+
+	return r
+}
+
+// SetRowDelay sets how long the server waits before sending each row, to simulate slow streaming.
+func (r *Response) SetRowDelay(delay time.Duration) *Response {
+	r.rowDelay = delay
+
+	return r
+}
+
+// SetDisconnectAfter causes the server to drop the connection once it has sent the given number of rows,
+// without completing the response.
+func (r *Response) SetDisconnectAfter(rows int) *Response {
+	r.disconnectAfter = rows
+
+	return r
+}
+
+// SetPendingPolls sets the number of times that the status of a query started in async mode is reported as
+// running before it completes.
+func (r *Response) SetPendingPolls(polls int) *Response {
+	r.pendingPolls = polls
+
+	return r
+}
+
+func (r *Response) isError() bool {
+	return len(r.errors) > 0
+}
+
+func (r *Response) hasBody() bool {
+	return r.rows != nil || r.errors != nil
+}
+
+// plans returns the plans requested by the payload of a request, or nil if none were requested.
+func (r *Response) plans(payload map[string]interface{}) map[string]interface{} {
+	if r.plan == nil {
+		return nil
+	}
+
+	plan := r.plan
+
+	if format, _ := payload["plan-format"].(string); strings.EqualFold(format, "string") {
+		encoded, err := json.Marshal(plan)
+		if err != nil {
+			return nil
+		}
+
+		plan = string(encoded)
+	}
+
+	plans := make(map[string]interface{})
+
+	if requested, _ := payload["logical-plan"].(bool); requested {
+		plans["logicalPlan"] = plan
+	}
+
+	if requested, _ := payload["optimized-logical-plan"].(bool); requested {
+		plans["optimizedLogicalPlan"] = plan
+	}
+
+	if len(plans) == 0 {
+		return nil
+	}
+
+	return plans
+}
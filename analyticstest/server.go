@@ -0,0 +1,622 @@
+// Package analyticstest provides an in-process fake Analytics server for testing applications which use
+// cbanalytics, without needing a cluster.
+//
+// The server speaks enough of the Analytics REST API for the SDK to execute queries, both directly and in
+// async mode through query handles, and to cancel them. The response to each statement is scripted:
+//
+//	srv := analyticstest.NewServer()
+//	defer srv.Close()
+//
+//	srv.Handle("SELECT VALUE COUNT(*) FROM orders",
+//		analyticstest.Errors(analyticstest.Error{Code: 23007, Message: "busy", Retriable: true}),
+//		analyticstest.Rows(42),
+//	)
+//
+//	cluster, err := cbanalytics.NewCluster(srv.URL(), cbanalytics.NewBasicAuthCredential("user", "pass"))
+//
+// Successive requests for a statement receive each of its responses in turn, with the last response being
+// repeated once they have all been used. Any credentials are accepted.
+package analyticstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	requestPath        = "/api/v1/request"
+	statusPathPrefix   = "/api/v1/request/status/"
+	resultPathPrefix   = "/api/v1/request/result/"
+	activeRequestsPath = "/api/v1/active_requests"
+)
+
+// Request is a query request received by the server.
+type Request struct {
+	// Statement is the statement being queried.
+	Statement string
+
+	// ClientContextID is the client context ID sent with the request.
+	ClientContextID string
+
+	// Async indicates whether the query was started in async mode.
+	Async bool
+
+	// Payload is the full JSON body of the request.
+	Payload map[string]interface{}
+}
+
+// Cancellation is a request received by the server to cancel a query.
+type Cancellation struct {
+	// RequestID is the ID of the query to cancel, if it was cancelled by request ID.
+	RequestID string
+
+	// ClientContextID is the client context ID of the query to cancel, if it was cancelled by client context ID.
+	ClientContextID string
+}
+
+// Server is a fake Analytics server.
+type Server struct {
+	srv *httptest.Server
+
+	lock      sync.Mutex
+	scripts   map[string][]*Response
+	defaults  []*Response
+	requests  []Request
+	cancels   []Cancellation
+	active    map[string]*activeQuery
+	nextQuery int
+}
+
+// activeQuery is a query which is either still streaming, or was started in async mode and has not been
+// discarded.
+type activeQuery struct {
+	requestID       string
+	clientContextID string
+	response        *Response
+	plans           map[string]interface{}
+	cancelled       chan struct{}
+	cancelOnce      sync.Once
+
+	// The following are only used for async queries.
+	async        bool
+	pendingPolls int
+	discarded    bool
+}
+
+func (q *activeQuery) cancel() {
+	q.cancelOnce.Do(func() {
+		close(q.cancelled)
+	})
+}
+
+func (q *activeQuery) isCancelled() bool {
+	select {
+	case <-q.cancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// NewServer starts a new fake server. The server must be closed with Close once it is no longer needed.
+//
+// Statements which have not been scripted with Handle fail with a non-retriable error, unless SetDefault
+// has been used.
+func NewServer() *Server {
+	s := &Server{
+		srv:       nil,
+		lock:      sync.Mutex{},
+		scripts:   make(map[string][]*Response),
+		defaults:  nil,
+		requests:  nil,
+		cancels:   nil,
+		active:    make(map[string]*activeQuery),
+		nextQuery: 0,
+	}
+
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// URL returns the base URL of the server, which can be used as the connection string for cbanalytics.NewCluster.
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// Close shuts down the server, dropping any connections which are still open.
+func (s *Server) Close() {
+	s.lock.Lock()
+	for _, query := range s.active {
+		query.cancel()
+	}
+	s.lock.Unlock()
+
+	s.srv.CloseClientConnections()
+	s.srv.Close()
+}
+
+// Handle scripts the responses sent for a statement. Each request for the statement receives the next
+// response, with the last response being repeated once they have all been used.
+// Calling Handle again for the same statement replaces its responses.
+func (s *Server) Handle(statement string, responses ...*Response) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.scripts[statement] = responses
+}
+
+// SetDefault scripts the responses sent for any statement which has not been scripted with Handle.
+func (s *Server) SetDefault(responses ...*Response) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.defaults = responses
+}
+
+// Requests returns the query requests received by the server, in the order that they were received.
+func (s *Server) Requests() []Request {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// Cancellations returns the cancel requests received by the server, in the order that they were received.
+func (s *Server) Cancellations() []Cancellation {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]Cancellation(nil), s.cancels...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == requestPath && r.Method == http.MethodPost:
+		s.serveQuery(w, r)
+	case r.URL.Path == activeRequestsPath && r.Method == http.MethodDelete:
+		s.serveCancel(w, r)
+	case strings.HasPrefix(r.URL.Path, statusPathPrefix) && r.Method == http.MethodGet:
+		s.serveStatus(w, strings.TrimPrefix(r.URL.Path, statusPathPrefix))
+	case strings.HasPrefix(r.URL.Path, resultPathPrefix) && r.Method == http.MethodGet:
+		s.serveResult(w, r, strings.TrimPrefix(r.URL.Path, resultPathPrefix))
+	case strings.HasPrefix(r.URL.Path, resultPathPrefix) && r.Method == http.MethodDelete:
+		s.serveDiscard(w, strings.TrimPrefix(r.URL.Path, resultPathPrefix))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *Server) serveQuery(w http.ResponseWriter, r *http.Request) {
+	var payload map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeErrors(w, http.StatusBadRequest, []Error{{Code: 24000, Message: "invalid request body", Retriable: false}})
+
+		return
+	}
+
+	statement, _ := payload["statement"].(string)
+	clientContextID, _ := payload["client_context_id"].(string)
+	mode, _ := payload["mode"].(string)
+
+	query := s.startQuery(Request{
+		Statement:       statement,
+		ClientContextID: clientContextID,
+		Async:           mode == "async",
+		Payload:         payload,
+	})
+
+	response := query.response
+
+	// Requests which fail before the query runs are rejected up front, in async mode as well.
+	if mode != "async" || response.httpStatus != http.StatusOK {
+		defer s.finishQuery(query.requestID)
+
+		s.writeResponse(w, r, query, response.rows, response.errors)
+
+		return
+	}
+
+	if !wait(r, query, response.delay) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"requestID":       query.requestID,
+		"clientContextID": query.clientContextID,
+		"handle":          statusPathPrefix + query.requestID,
+		"status":          "running",
+	})
+}
+
+// startQuery records a request and registers it as active, choosing the response for it.
+func (s *Server) startQuery(req Request) *activeQuery {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.requests = append(s.requests, req)
+	s.nextQuery++
+
+	query := &activeQuery{
+		requestID:       fmt.Sprintf("req-%d", s.nextQuery),
+		clientContextID: req.ClientContextID,
+		response:        s.nextResponseLocked(req.Statement),
+		plans:           nil,
+		cancelled:       make(chan struct{}),
+		cancelOnce:      sync.Once{},
+		async:           req.Async,
+		pendingPolls:    0,
+		discarded:       false,
+	}
+	query.pendingPolls = query.response.pendingPolls
+	query.plans = query.response.plans(req.Payload)
+
+	s.active[query.requestID] = query
+
+	return query
+}
+
+func (s *Server) nextResponseLocked(statement string) *Response {
+	responses, ok := s.scripts[statement]
+	if !ok {
+		responses = s.defaults
+	}
+
+	if len(responses) == 0 {
+		return Errors(Error{
+			Code:      24000,
+			Message:   fmt.Sprintf("analyticstest: no response scripted for statement: %s", statement),
+			Retriable: false,
+		})
+	}
+
+	response := responses[0]
+	if len(responses) > 1 {
+		if ok {
+			s.scripts[statement] = responses[1:]
+		} else {
+			s.defaults = responses[1:]
+		}
+	}
+
+	return response
+}
+
+func (s *Server) finishQuery(requestID string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.active, requestID)
+}
+
+func (s *Server) lookupAsyncQuery(requestID string) *activeQuery {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	query, ok := s.active[requestID]
+	if !ok || !query.async || query.discarded {
+		return nil
+	}
+
+	return query
+}
+
+func (s *Server) serveCancel(w http.ResponseWriter, r *http.Request) {
+	// ParseForm ignores the body of DELETE requests.
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	cancellation := Cancellation{
+		RequestID:       form.Get("request_id"),
+		ClientContextID: form.Get("client_context_id"),
+	}
+
+	s.lock.Lock()
+	s.cancels = append(s.cancels, cancellation)
+
+	found := false
+
+	for _, query := range s.active {
+		if (cancellation.RequestID != "" && query.requestID == cancellation.RequestID) ||
+			(cancellation.ClientContextID != "" && query.clientContextID == cancellation.ClientContextID) {
+			query.cancel()
+
+			found = true
+		}
+	}
+	s.lock.Unlock()
+
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) serveStatus(w http.ResponseWriter, requestID string) {
+	query := s.lookupAsyncQuery(requestID)
+	if query == nil {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{})
+
+		return
+	}
+
+	s.lock.Lock()
+	pending := query.pendingPolls > 0
+	if pending {
+		query.pendingPolls--
+	}
+	s.lock.Unlock()
+
+	switch {
+	case query.isCancelled():
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"status": "cancelled",
+		})
+	case pending:
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"status": "running",
+		})
+	case query.response.isError():
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"status": "fatal",
+			"errors": jsonErrors(query.response.errors),
+		})
+	default:
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"status": "success",
+			"handle": resultPathPrefix + query.requestID,
+			"metrics": map[string]interface{}{
+				"resultCount": len(query.response.rows),
+			},
+		})
+	}
+}
+
+func (s *Server) serveResult(w http.ResponseWriter, r *http.Request, requestID string) {
+	query := s.lookupAsyncQuery(requestID)
+	if query == nil || query.response.isError() {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{})
+
+		return
+	}
+
+	rows := query.response.rows
+	if rows == nil {
+		rows = []interface{}{}
+	}
+
+	s.writeResponse(w, r, query, rows, nil)
+}
+
+func (s *Server) serveDiscard(w http.ResponseWriter, requestID string) {
+	s.lock.Lock()
+	query, ok := s.active[requestID]
+	if ok && query.async {
+		query.discarded = true
+		delete(s.active, requestID)
+	}
+	s.lock.Unlock()
+
+	if !ok || !query.async {
+		w.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// writeResponse streams a query response. The stream stops early if the client disconnects or the query is
+// cancelled, in which case the connection is dropped.
+func (s *Server) writeResponse(w http.ResponseWriter, r *http.Request, query *activeQuery, rows []interface{},
+	errs []Error) {
+	response := query.response
+	start := time.Now()
+
+	if !wait(r, query, response.delay) {
+		return
+	}
+
+	if !response.hasBody() {
+		writeJSON(w, response.httpStatus, map[string]interface{}{})
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.httpStatus)
+
+	var buf bytes.Buffer
+
+	buf.WriteString(`{"requestID":`)
+	writeJSONValue(&buf, query.requestID)
+
+	if query.clientContextID != "" {
+		buf.WriteString(`,"clientContextID":`)
+		writeJSONValue(&buf, query.clientContextID)
+	}
+
+	buf.WriteString(`,"signature":{"*":"*"}`)
+
+	resultSize := 0
+
+	if rows != nil {
+		buf.WriteString(`,"results":[`)
+
+		for i, row := range rows {
+			if i == response.disconnectAfter {
+				// The separator is sent so that the client can tell that the last row sent is complete.
+				buf.WriteString(",")
+				_, _ = w.Write(buf.Bytes())
+				disconnect(w)
+
+				return
+			}
+
+			if response.rowDelay > 0 {
+				_, _ = w.Write(buf.Bytes())
+				buf.Reset()
+				flush(w)
+
+				if !wait(r, query, response.rowDelay) {
+					disconnect(w)
+
+					return
+				}
+			}
+
+			if i > 0 {
+				buf.WriteString(",")
+			}
+
+			rowBytes, err := json.Marshal(row)
+			if err != nil {
+				rowBytes = []byte("null")
+			}
+
+			resultSize += len(rowBytes)
+
+			buf.Write(rowBytes)
+		}
+
+		if response.disconnectAfter >= len(rows) {
+			buf.WriteString(",")
+			_, _ = w.Write(buf.Bytes())
+			disconnect(w)
+
+			return
+		}
+
+		buf.WriteString("]")
+	}
+
+	if query.plans != nil {
+		buf.WriteString(`,"plans":`)
+		writeJSONValue(&buf, query.plans)
+	}
+
+	status := "success"
+
+	if len(errs) > 0 {
+		status = "fatal"
+
+		buf.WriteString(`,"errors":`)
+		writeJSONValue(&buf, jsonErrors(errs))
+	}
+
+	elapsed := time.Since(start).String()
+
+	buf.WriteString(`,"status":`)
+	writeJSONValue(&buf, status)
+	buf.WriteString(`,"metrics":`)
+	writeJSONValue(&buf, map[string]interface{}{
+		"elapsedTime":      elapsed,
+		"executionTime":    elapsed,
+		"resultCount":      len(rows),
+		"resultSize":       resultSize,
+		"processedObjects": len(rows),
+	})
+	buf.WriteString("}")
+
+	_, _ = w.Write(buf.Bytes())
+}
+
+// wait waits for the delay, returning false if the client disconnects or the query is cancelled first.
+func wait(r *http.Request, query *activeQuery, delay time.Duration) bool {
+	if delay <= 0 {
+		return !query.isCancelled()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	case <-query.cancelled:
+		return false
+	}
+}
+
+func flush(w http.ResponseWriter) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// disconnect drops the connection without completing the response.
+func disconnect(w http.ResponseWriter) {
+	flush(w)
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return
+	}
+
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+
+	_ = conn.Close()
+}
+
+func jsonErrors(errs []Error) []map[string]interface{} {
+	out := make([]map[string]interface{}, len(errs))
+	for i, err := range errs {
+		out[i] = map[string]interface{}{
+			"code":      err.Code,
+			"msg":       err.Message,
+			"retriable": err.Retriable,
+		}
+	}
+
+	return out
+}
+
+func writeErrors(w http.ResponseWriter, statusCode int, errs []Error) {
+	writeJSON(w, statusCode, map[string]interface{}{
+		"errors": jsonErrors(errs),
+		"status": "fatal",
+	})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeJSONValue(buf *bytes.Buffer, value interface{}) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		b = []byte("null")
+	}
+
+	buf.Write(b)
+}
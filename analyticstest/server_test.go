@@ -0,0 +1,311 @@
+package analyticstest_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cbanalytics "github.com/couchbase/gocbanalytics"
+	"github.com/couchbase/gocbanalytics/analyticstest"
+)
+
+func newCluster(t *testing.T, srv *analyticstest.Server) *cbanalytics.Cluster {
+	t.Helper()
+
+	cluster, err := cbanalytics.NewCluster(srv.URL(), cbanalytics.NewBasicAuthCredential("user", "pass"),
+		cbanalytics.NewClusterOptions().SetRetryStrategy(
+			cbanalytics.NewExponentialBackoffRetryStrategy(time.Millisecond, time.Millisecond, 2)))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = cluster.Close()
+	})
+
+	return cluster
+}
+
+func TestRows(t *testing.T) {
+	srv := analyticstest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("SELECT name FROM t", analyticstest.Rows(
+		map[string]interface{}{"name": "a"},
+		map[string]interface{}{"name": "b"},
+	))
+
+	cluster := newCluster(t, srv)
+
+	res, err := cluster.ExecuteQuery(context.Background(), "SELECT name FROM t",
+		cbanalytics.NewQueryOptions().SetClientContextID("ctx-1"))
+	require.NoError(t, err)
+
+	rows, meta, err := cbanalytics.BufferQueryResult[map[string]string](res)
+	require.NoError(t, err)
+
+	assert.Equal(t, []map[string]string{{"name": "a"}, {"name": "b"}}, rows)
+	assert.Equal(t, uint64(2), meta.Metrics.ResultCount)
+
+	requests := srv.Requests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "SELECT name FROM t", requests[0].Statement)
+	assert.Equal(t, "ctx-1", requests[0].ClientContextID)
+	assert.False(t, requests[0].Async)
+}
+
+func TestRetriableErrorThenRows(t *testing.T) {
+	srv := analyticstest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("SELECT 1",
+		analyticstest.Errors(analyticstest.Error{Code: 23000, Message: "unavailable", Retriable: true}).
+			SetHTTPStatus(http.StatusServiceUnavailable),
+		analyticstest.Errors(analyticstest.Error{Code: 23007, Message: "busy", Retriable: true}),
+		analyticstest.Rows(1),
+	)
+
+	cluster := newCluster(t, srv)
+
+	count, err := cbanalytics.QueryScalar[int](context.Background(), cluster, "SELECT 1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	assert.Len(t, srv.Requests(), 3)
+}
+
+func TestNonRetriableError(t *testing.T) {
+	srv := analyticstest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("SELEC 1", analyticstest.Errors(analyticstest.Error{Code: 24000, Message: "syntax error"}))
+
+	cluster := newCluster(t, srv)
+
+	_, err := cluster.ExecuteQuery(context.Background(), "SELEC 1")
+	require.ErrorIs(t, err, cbanalytics.ErrQuery)
+
+	var qErr *cbanalytics.QueryError
+	require.ErrorAs(t, err, &qErr)
+	assert.Equal(t, 24000, qErr.Code())
+	assert.Len(t, srv.Requests(), 1)
+}
+
+func TestRowsThenErrors(t *testing.T) {
+	srv := analyticstest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("SELECT VALUE n FROM t", analyticstest.Rows(1, 2).
+		SetErrors(analyticstest.Error{Code: 23007, Message: "job failed"}))
+
+	cluster := newCluster(t, srv)
+
+	res, err := cluster.ExecuteQuery(context.Background(), "SELECT VALUE n FROM t")
+	require.NoError(t, err)
+
+	var rows []int
+
+	for row := res.NextRow(); row != nil; row = res.NextRow() {
+		var value int
+		require.NoError(t, row.ContentAs(&value))
+
+		rows = append(rows, value)
+	}
+
+	assert.Equal(t, []int{1, 2}, rows)
+
+	var qErr *cbanalytics.QueryError
+	require.ErrorAs(t, res.Err(), &qErr)
+	assert.Equal(t, 23007, qErr.Code())
+}
+
+func TestPlans(t *testing.T) {
+	srv := analyticstest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("SELECT VALUE 1", analyticstest.Rows(1).SetPlan(map[string]interface{}{"operator": "distribute-result"}))
+
+	cluster := newCluster(t, srv)
+
+	res, err := cluster.ExecuteQuery(context.Background(), "SELECT VALUE 1", cbanalytics.NewQueryOptions().
+		SetPlanOptions(cbanalytics.NewQueryPlanOptions().SetOptimizedLogicalPlan(true)))
+	require.NoError(t, err)
+
+	_, meta, err := cbanalytics.BufferQueryResult[int](res)
+	require.NoError(t, err)
+
+	require.NotNil(t, meta.Plans)
+	assert.Nil(t, meta.Plans.LogicalPlan)
+
+	plan, err := cbanalytics.ParseQueryPlan(meta.Plans.OptimizedLogicalPlan)
+	require.NoError(t, err)
+	assert.Equal(t, "distribute-result", plan.Operator)
+
+	res, err = cluster.ExecuteQuery(context.Background(), "SELECT VALUE 1", cbanalytics.NewQueryOptions().
+		SetPlanOptions(cbanalytics.NewQueryPlanOptions().SetLogicalPlan(true).
+			SetFormat(cbanalytics.QueryPlanFormatString)))
+	require.NoError(t, err)
+
+	_, meta, err = cbanalytics.BufferQueryResult[int](res)
+	require.NoError(t, err)
+
+	require.NotNil(t, meta.Plans)
+	assert.JSONEq(t, `"{\"operator\":\"distribute-result\"}"`, string(meta.Plans.LogicalPlan))
+}
+
+func TestUnscriptedStatement(t *testing.T) {
+	srv := analyticstest.NewServer()
+	defer srv.Close()
+
+	cluster := newCluster(t, srv)
+
+	_, err := cluster.ExecuteQuery(context.Background(), "SELECT 1")
+	require.ErrorIs(t, err, cbanalytics.ErrQuery)
+
+	srv.SetDefault(analyticstest.Rows(2))
+
+	value, err := cbanalytics.QueryScalar[int](context.Background(), cluster, "SELECT 2")
+	require.NoError(t, err)
+	assert.Equal(t, 2, value)
+}
+
+func TestSlowStreamingTimeoutCancels(t *testing.T) {
+	srv := analyticstest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("SELECT slow", analyticstest.Rows(1, 2, 3).SetRowDelay(time.Second))
+
+	cluster := newCluster(t, srv)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	res, err := cluster.ExecuteQuery(ctx, "SELECT slow", cbanalytics.NewQueryOptions().SetClientContextID("ctx-1"))
+	require.NoError(t, err)
+
+	require.NotNil(t, res.NextRow())
+
+	cancel()
+
+	for res.NextRow() != nil {
+	}
+
+	require.Error(t, res.Err())
+
+	require.Eventually(t, func() bool {
+		return len(srv.Cancellations()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, "req-1", srv.Cancellations()[0].RequestID)
+}
+
+func TestDisconnectMidStream(t *testing.T) {
+	srv := analyticstest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("SELECT 1", analyticstest.Rows(1, 2, 3).SetDisconnectAfter(2))
+
+	cluster := newCluster(t, srv)
+
+	res, err := cluster.ExecuteQuery(context.Background(), "SELECT 1")
+	require.NoError(t, err)
+
+	var values []int
+
+	for value, err := range cbanalytics.RowsAs[int](res) {
+		if err != nil {
+			break
+		}
+
+		values = append(values, value)
+	}
+
+	assert.Equal(t, []int{1, 2}, values)
+	require.Error(t, res.Err())
+}
+
+func TestHTTPStatus(t *testing.T) {
+	srv := analyticstest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("SELECT 1", analyticstest.HTTPStatus(http.StatusServiceUnavailable))
+
+	cluster := newCluster(t, srv)
+
+	_, err := cluster.ExecuteQuery(context.Background(), "SELECT 1")
+	require.ErrorIs(t, err, cbanalytics.ErrServiceUnavailable)
+}
+
+func TestAsyncQuery(t *testing.T) {
+	srv := analyticstest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("SELECT VALUE i FROM RANGE(1, 3) AS i", analyticstest.Rows(1, 2, 3).SetPendingPolls(2))
+
+	cluster := newCluster(t, srv)
+
+	handle, err := cluster.StartQuery(context.Background(), "SELECT VALUE i FROM RANGE(1, 3) AS i")
+	require.NoError(t, err)
+
+	var polls int
+
+	resultHandle, err := handle.Wait(context.Background(), cbanalytics.NewWaitOptions().
+		SetInitialInterval(time.Millisecond).
+		SetProgress(func(*cbanalytics.QueryStatus) {
+			polls++
+		}))
+	require.NoError(t, err)
+	assert.Equal(t, 2, polls)
+
+	res, err := resultHandle.FetchResults(context.Background())
+	require.NoError(t, err)
+
+	rows, _, err := cbanalytics.BufferQueryResult[int](res)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, rows)
+
+	require.NoError(t, resultHandle.DiscardResults(context.Background()))
+
+	_, err = resultHandle.FetchResults(context.Background())
+	require.ErrorIs(t, err, cbanalytics.ErrQueryNotFound)
+
+	assert.True(t, srv.Requests()[0].Async)
+}
+
+func TestAsyncQueryError(t *testing.T) {
+	srv := analyticstest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("SELECT 1", analyticstest.Errors(analyticstest.Error{Code: 23007, Message: "job failed"}))
+
+	cluster := newCluster(t, srv)
+
+	handle, err := cluster.StartQuery(context.Background(), "SELECT 1")
+	require.NoError(t, err)
+
+	_, err = handle.Wait(context.Background(), cbanalytics.NewWaitOptions().SetInitialInterval(time.Millisecond))
+	require.ErrorIs(t, err, cbanalytics.ErrQuery)
+}
+
+func TestAsyncQueryCancel(t *testing.T) {
+	srv := analyticstest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("SELECT 1", analyticstest.Rows(1).SetPendingPolls(100))
+
+	cluster := newCluster(t, srv)
+
+	handle, err := cluster.StartQuery(context.Background(), "SELECT 1")
+	require.NoError(t, err)
+
+	require.NoError(t, handle.Cancel(context.Background()))
+
+	status, err := handle.FetchStatus(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, cbanalytics.QueryStatusStateCancelled, status.State())
+
+	require.Len(t, srv.Cancellations(), 1)
+	assert.Equal(t, "req-1", srv.Cancellations()[0].RequestID)
+}
@@ -0,0 +1,92 @@
+// Package cbanalyticsotel provides an OpenTelemetry implementation of the cbanalytics RequestTracer.
+//
+// Spans are parented from the context.Context passed to each cbanalytics operation, so that Analytics
+// operations appear within the traces of the surrounding application.
+//
+// The package is a separate module, so that applications which do not use OpenTelemetry do not depend on it.
+package cbanalyticsotel
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	cbanalytics "github.com/couchbase/gocbanalytics"
+)
+
+const instrumentationName = "github.com/couchbase/gocbanalytics"
+
+// OpenTelemetryRequestTracer is a cbanalytics.RequestTracer which creates OpenTelemetry spans.
+type OpenTelemetryRequestTracer struct {
+	tracer trace.Tracer
+}
+
+// NewOpenTelemetryRequestTracer creates a new OpenTelemetryRequestTracer using the given TracerProvider.
+func NewOpenTelemetryRequestTracer(provider trace.TracerProvider) *OpenTelemetryRequestTracer {
+	return &OpenTelemetryRequestTracer{
+		tracer: provider.Tracer(instrumentationName, trace.WithInstrumentationVersion(cbanalytics.Version())),
+	}
+}
+
+// RequestSpan creates a new span. The parentContext is expected to be a context.Context, any other value
+// results in a root span being created.
+func (t *OpenTelemetryRequestTracer) RequestSpan(parentContext cbanalytics.RequestSpanContext,
+	operationName string) cbanalytics.RequestSpan {
+	parentCtx := context.Background()
+	if ctx, ok := parentContext.(context.Context); ok && ctx != nil {
+		parentCtx = ctx
+	}
+
+	ctx, span := t.tracer.Start(parentCtx, operationName, trace.WithSpanKind(trace.SpanKindClient))
+
+	return &OpenTelemetryRequestSpan{
+		ctx:  ctx,
+		span: span,
+	}
+}
+
+// OpenTelemetryRequestSpan is a cbanalytics.RequestSpan which wraps an OpenTelemetry span.
+type OpenTelemetryRequestSpan struct {
+	ctx  context.Context
+	span trace.Span
+}
+
+// End completes the span.
+func (s *OpenTelemetryRequestSpan) End() {
+	s.span.End()
+}
+
+// Context returns a context.Context containing the span, for use as the parent of child spans.
+func (s *OpenTelemetryRequestSpan) Context() cbanalytics.RequestSpanContext {
+	return s.ctx
+}
+
+// SetAttribute sets an attribute on the span.
+func (s *OpenTelemetryRequestSpan) SetAttribute(key string, value interface{}) {
+	s.span.SetAttributes(toAttribute(key, value))
+}
+
+func toAttribute(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int32:
+		return attribute.Int64(key, int64(v))
+	case int64:
+		return attribute.Int64(key, v)
+	case uint32:
+		return attribute.Int64(key, int64(v))
+	case float64:
+		return attribute.Float64(key, v)
+	case []string:
+		return attribute.StringSlice(key, v)
+	default:
+		return attribute.String(key, fmt.Sprintf("%v", v))
+	}
+}
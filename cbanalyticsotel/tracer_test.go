@@ -0,0 +1,62 @@
+package cbanalyticsotel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRequestSpanParenting(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	tracer := NewOpenTelemetryRequestTracer(provider)
+
+	appCtx, appSpan := provider.Tracer("app").Start(context.Background(), "handler")
+
+	opSpan := tracer.RequestSpan(appCtx, "execute_query")
+	opSpan.SetAttribute("db.statement", "SELECT 1")
+
+	attemptSpan := tracer.RequestSpan(opSpan.Context(), "dispatch_to_server")
+	attemptSpan.SetAttribute("db.couchbase.retries", uint32(2))
+	attemptSpan.SetAttribute("server.port", 8095)
+	attemptSpan.End()
+
+	opSpan.End()
+	appSpan.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	attempt, op, app := spans[0], spans[1], spans[2]
+
+	assert.Equal(t, "dispatch_to_server", attempt.Name())
+	assert.Equal(t, "execute_query", op.Name())
+	assert.Equal(t, trace.SpanKindClient, op.SpanKind())
+
+	assert.Equal(t, app.SpanContext().SpanID(), op.Parent().SpanID())
+	assert.Equal(t, op.SpanContext().SpanID(), attempt.Parent().SpanID())
+	assert.Equal(t, app.SpanContext().TraceID(), attempt.SpanContext().TraceID())
+
+	assert.Contains(t, op.Attributes(), attribute.String("db.statement", "SELECT 1"))
+	assert.Contains(t, attempt.Attributes(), attribute.Int64("db.couchbase.retries", 2))
+	assert.Contains(t, attempt.Attributes(), attribute.Int("server.port", 8095))
+}
+
+func TestRequestSpanNonContextParent(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	span := NewOpenTelemetryRequestTracer(provider).RequestSpan(nil, "fetch_status")
+	span.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.False(t, spans[0].Parent().IsValid())
+}
@@ -0,0 +1,201 @@
+package cbanalyticssql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	cbanalytics "github.com/couchbase/gocbanalytics"
+)
+
+var errTransactionsNotSupported = errors.New("transactions are not supported by analytics")
+
+type analyticsConn struct {
+	querier cbanalytics.Querier
+
+	// owner is set when the connection was created by Driver.Open, in which case it owns its connector.
+	owner *connector
+}
+
+func (c *analyticsConn) Prepare(query string) (driver.Stmt, error) {
+	return &analyticsStmt{
+		conn:  c,
+		query: query,
+	}, nil
+}
+
+func (c *analyticsConn) PrepareContext(_ context.Context, query string) (driver.Stmt, error) {
+	return c.Prepare(query)
+}
+
+func (c *analyticsConn) Close() error {
+	if c.owner == nil {
+		return nil
+	}
+
+	return c.owner.Close()
+}
+
+func (c *analyticsConn) Begin() (driver.Tx, error) {
+	return nil, errTransactionsNotSupported
+}
+
+func (c *analyticsConn) BeginTx(_ context.Context, _ driver.TxOptions) (driver.Tx, error) {
+	return nil, errTransactionsNotSupported
+}
+
+func (c *analyticsConn) Ping(ctx context.Context) error {
+	rows, err := c.QueryContext(ctx, "SELECT 1", nil)
+	if err != nil {
+		return err
+	}
+
+	return rows.Close()
+}
+
+// CheckNamedValue accepts any argument, as arguments are sent to the server as JSON.
+func (c *analyticsConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if valuer, ok := nv.Value.(driver.Valuer); ok {
+		val, err := valuer.Value()
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+
+		nv.Value = val
+	}
+
+	return nil
+}
+
+func (c *analyticsConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	opts, err := queryOptions(args)
+	if err != nil {
+		return nil, err
+	}
+
+	// The stream outlives this call, so it gets its own context which is cancelled once the rows are closed.
+	streamCtx, cancel := context.WithCancel(ctx)
+
+	res, err := c.querier.ExecuteQuery(streamCtx, query, opts)
+	if err != nil {
+		cancel()
+
+		return nil, err //nolint:wrapcheck
+	}
+
+	rows, err := newAnalyticsRows(res, cancel)
+	if err != nil {
+		// Closing the result releases the response body, and the slot in the concurrency limiter.
+		_ = res.Close()
+
+		cancel()
+
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+func (c *analyticsConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	opts, err := queryOptions(args)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.querier.ExecuteQuery(ctx, query, opts)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	for row := res.NextRow(); row != nil; row = res.NextRow() {
+	}
+
+	if err := res.Err(); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return driver.ResultNoRows, nil
+}
+
+// queryOptions maps the arguments onto positional or named parameters.
+func queryOptions(args []driver.NamedValue) (*cbanalytics.QueryOptions, error) {
+	// Rows are always decoded as JSON, regardless of any unmarshaler configured on the cluster.
+	opts := cbanalytics.NewQueryOptions().SetUnmarshaler(cbanalytics.NewJSONUnmarshaler())
+	if len(args) == 0 {
+		return opts, nil
+	}
+
+	var positional []interface{}
+
+	var named map[string]interface{}
+
+	for _, arg := range args {
+		if arg.Name == "" {
+			positional = append(positional, arg.Value)
+
+			continue
+		}
+
+		if named == nil {
+			named = make(map[string]interface{}, len(args))
+		}
+
+		named[arg.Name] = arg.Value
+	}
+
+	if positional != nil && named != nil {
+		return nil, fmt.Errorf("cannot mix positional and named arguments") //nolint:err113
+	}
+
+	if positional != nil {
+		opts.SetPositionalParameters(positional)
+	} else {
+		opts.SetNamedParameters(named)
+	}
+
+	return opts, nil
+}
+
+type analyticsStmt struct {
+	conn  *analyticsConn
+	query string
+}
+
+func (s *analyticsStmt) Close() error {
+	return nil
+}
+
+// NumInput returns -1 as the number of placeholders is not known to the driver.
+func (s *analyticsStmt) NumInput() int {
+	return -1
+}
+
+func (s *analyticsStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *analyticsStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *analyticsStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *analyticsStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{
+			Name:    "",
+			Ordinal: i + 1,
+			Value:   arg,
+		}
+	}
+
+	return named
+}
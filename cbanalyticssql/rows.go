@@ -0,0 +1,248 @@
+package cbanalyticssql
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	cbanalytics "github.com/couchbase/gocbanalytics"
+)
+
+// valueColumnName is the name of the single column used for rows which are not objects.
+const valueColumnName = "$1"
+
+type analyticsRows struct {
+	result *cbanalytics.QueryResult
+	cancel context.CancelFunc
+
+	columns []string
+
+	// peeked is the first row, which is read ahead when the columns are derived from it.
+	peeked json.RawMessage
+	closed bool
+}
+
+func newAnalyticsRows(result *cbanalytics.QueryResult, cancel context.CancelFunc) (*analyticsRows, error) {
+	rows := &analyticsRows{
+		result:  result,
+		cancel:  cancel,
+		columns: nil,
+		peeked:  nil,
+		closed:  false,
+	}
+
+	if columns := signatureColumns(result.Signature()); columns != nil {
+		rows.columns = columns
+
+		return rows, nil
+	}
+
+	row := result.NextRow()
+	if row == nil {
+		if err := result.Err(); err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+
+		rows.columns = []string{}
+
+		return rows, nil
+	}
+
+	var raw json.RawMessage
+	if err := row.ContentAs(&raw); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	rows.peeked = raw
+
+	keys, _, isObject, err := decodeObject(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if isObject {
+		rows.columns = keys
+	} else {
+		rows.columns = []string{valueColumnName}
+	}
+
+	return rows, nil
+}
+
+func (r *analyticsRows) Columns() []string {
+	return r.columns
+}
+
+func (r *analyticsRows) Close() error {
+	if r.closed {
+		return nil
+	}
+
+	r.closed = true
+	err := r.result.Close()
+	r.cancel()
+
+	return err //nolint:wrapcheck
+}
+
+func (r *analyticsRows) Next(dest []driver.Value) error {
+	if r.closed {
+		return io.EOF
+	}
+
+	raw := r.peeked
+	r.peeked = nil
+
+	if raw == nil {
+		row := r.result.NextRow()
+		if row == nil {
+			if err := r.result.Err(); err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			return io.EOF
+		}
+
+		if err := row.ContentAs(&raw); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+
+	_, values, isObject, err := decodeObject(raw)
+	if err != nil {
+		return err
+	}
+
+	for i := range dest {
+		dest[i] = nil
+	}
+
+	if !isObject {
+		if len(r.columns) == 1 && r.columns[0] == valueColumnName && len(dest) > 0 {
+			dest[0], err = toDriverValue(raw)
+		}
+
+		return err
+	}
+
+	for i, column := range r.columns {
+		if i >= len(dest) {
+			break
+		}
+
+		value, ok := values[column]
+		if !ok {
+			continue
+		}
+
+		dest[i], err = toDriverValue(value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// signatureColumns returns the field names from a signature, or nil if the signature does not name them.
+func signatureColumns(signature json.RawMessage) []string {
+	if len(signature) == 0 {
+		return nil
+	}
+
+	keys, _, isObject, err := decodeObject(signature)
+	if err != nil || !isObject || len(keys) == 0 {
+		return nil
+	}
+
+	for _, key := range keys {
+		if key == "*" {
+			return nil
+		}
+	}
+
+	return keys
+}
+
+// decodeObject decodes a JSON object, returning its keys in the order they appear.
+// If the value is not an object then isObject is false.
+func decodeObject(raw []byte) (keys []string, values map[string]json.RawMessage, isObject bool, err error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, nil, false, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, false, fmt.Errorf("failed to decode row: %w", err)
+	}
+
+	values = make(map[string]json.RawMessage)
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("failed to decode row: %w", err)
+		}
+
+		key, _ := tok.(string)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, nil, false, fmt.Errorf("failed to decode row: %w", err)
+		}
+
+		if _, exists := values[key]; !exists {
+			keys = append(keys, key)
+		}
+
+		values[key] = value
+	}
+
+	return keys, values, true, nil
+}
+
+// toDriverValue converts a JSON value into a driver.Value. Objects and arrays are returned as raw JSON.
+func toDriverValue(raw json.RawMessage) (driver.Value, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+
+	switch trimmed[0] {
+	case 'n':
+		return nil, nil
+	case 't', 'f':
+		var b bool
+		if err := json.Unmarshal(trimmed, &b); err != nil {
+			return nil, fmt.Errorf("failed to decode value: %w", err)
+		}
+
+		return b, nil
+	case '"':
+		var s string
+		if err := json.Unmarshal(trimmed, &s); err != nil {
+			return nil, fmt.Errorf("failed to decode value: %w", err)
+		}
+
+		return s, nil
+	case '{', '[':
+		return []byte(string(trimmed)), nil
+	default:
+		if i, err := strconv.ParseInt(string(trimmed), 10, 64); err == nil {
+			return i, nil
+		}
+
+		f, err := strconv.ParseFloat(string(trimmed), 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode value: %w", err)
+		}
+
+		return f, nil
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"net/http"
 	"os"
 	"time"
 
 	"github.com/couchbase/gocbanalytics/internal/httpqueryclient"
+	"github.com/couchbase/gocbanalytics/internal/recordreplay"
 )
 
 type clusterClient interface {
@@ -30,10 +32,20 @@ type clusterClientOptions struct {
 	ServerQueryTimeout                   time.Duration
 	TrustOnly                            TrustOnly
 	DisableServerCertificateVerification *bool
-	Address                              address
+	Addresses                            []address
 	Unmarshaler                          Unmarshaler
 	Logger                               Logger
 	MaxRetries                           uint32
+	RetryStrategy                        RetryStrategy
+	Tracer                               RequestTracer
+	Meter                                Meter
+	RecordReplay                         *RecordReplayOptions
+	WrapTransport                        func(http.RoundTripper) http.RoundTripper
+	Transport                            httpqueryclient.TransportConfig
+	Limiter                              httpqueryclient.LimiterConfig
+	RetryBudget                          httpqueryclient.RetryBudgetConfig
+	CircuitBreaker                       httpqueryclient.CircuitBreakerConfig
+	Redaction                            httpqueryclient.RedactionLevel
 }
 
 func newClusterClient(opts clusterClientOptions) (clusterClient, error) {
@@ -48,6 +60,8 @@ type httpClusterClient struct {
 	serverQueryTimeout time.Duration
 	unmarshaler        Unmarshaler
 	logger             Logger
+	tracer             RequestTracer
+	meter              Meter
 	maxRetries         uint32
 }
 
@@ -102,7 +116,7 @@ func newHTTPClusterClient(opts clusterClientOptions) (*httpClusterClient, error)
 			pool = nil
 		}
 
-		tlsConfig = createTLSConfig(opts.Address.Host, pool)
+		tlsConfig = createTLSConfig(opts.Addresses[0].Host, pool)
 		// Always set GetClientCertificate so that certificate auth works if the credential is changed at
 		// runtime via SetCredential. When the active credential is not a CertificateCredential an empty
 		// certificate is returned, which is the same behavior as not setting this callback at all.
@@ -122,13 +136,64 @@ func newHTTPClusterClient(opts clusterClientOptions) (*httpClusterClient, error)
 		}
 	}
 
+	var (
+		recordReplayMode recordreplay.Mode
+		recorder         *recordreplay.Transport
+	)
+
+	if opts.RecordReplay != nil {
+		recordReplayMode = recordreplay.ModeRecord
+		if *opts.RecordReplay.Mode == RecordReplayModeReplay {
+			recordReplayMode = recordreplay.ModeReplay
+		}
+
+		// The transport of every endpoint goes through the same recorder, so that a request which fails over
+		// between endpoints is recorded and replayed as one sequence of responses.
+		recorder = recordreplay.NewTransport(recordReplayMode, *opts.RecordReplay.Directory)
+	}
+
 	clientOpts := httpqueryclient.ClientConfig{
 		TLSConfig:      tlsConfig,
 		Logger:         opts.Logger,
 		ConnectTimeout: opts.ConnectTimeout,
+		Transport:      opts.Transport,
+		Limiter:        opts.Limiter,
+		RetryBudget:    opts.RetryBudget,
+		CircuitBreaker: opts.CircuitBreaker,
+		Redaction:      opts.Redaction,
+		RetryStrategy:  newRetryStrategyWrapper(opts.RetryStrategy),
+		WrapTransport:  nil,
+		// Replayed responses are served without contacting the server, so its hosts need not be resolvable.
+		SkipHostLookup: recordReplayMode == recordreplay.ModeReplay,
+	}
+
+	if opts.WrapTransport != nil || recorder != nil {
+		clientOpts.WrapTransport = func(transport http.RoundTripper) http.RoundTripper {
+			if opts.WrapTransport != nil {
+				if wrapped := opts.WrapTransport(transport); wrapped != nil {
+					transport = wrapped
+				}
+			}
+
+			// Recording happens outside of any user supplied transport, so that replaying serves responses
+			// without invoking it.
+			if recorder != nil {
+				transport = recorder.Wrap(transport)
+			}
+
+			return transport
+		}
+	}
+
+	endpoints := make([]httpqueryclient.Endpoint, len(opts.Addresses))
+	for i, addr := range opts.Addresses {
+		endpoints[i] = httpqueryclient.Endpoint{
+			Host: addr.Host,
+			Port: addr.Port,
+		}
 	}
 
-	client := httpqueryclient.NewClient(opts.Scheme, opts.Address.Host, opts.Address.Port, clientOpts)
+	client := httpqueryclient.NewClient(opts.Scheme, endpoints, clientOpts)
 
 	return &httpClusterClient{
 		scheme:             opts.Scheme,
@@ -137,6 +202,8 @@ func newHTTPClusterClient(opts clusterClientOptions) (*httpClusterClient, error)
 		serverQueryTimeout: opts.ServerQueryTimeout,
 		unmarshaler:        opts.Unmarshaler,
 		logger:             opts.Logger,
+		tracer:             opts.Tracer,
+		meter:              opts.Meter,
 		maxRetries:         opts.MaxRetries,
 	}, nil
 }
@@ -149,6 +216,8 @@ func (c *httpClusterClient) Database(name string) databaseClient {
 		DefaultServerTimeout: c.serverQueryTimeout,
 		DefaultUnmarshaler:   c.unmarshaler,
 		Logger:               c.logger,
+		Tracer:               c.tracer,
+		Meter:                c.meter,
 		DefaultMaxRetries:    c.maxRetries,
 	})
 }
@@ -161,6 +230,8 @@ func (c *httpClusterClient) QueryClient() queryClient {
 		DefaultUnmarshaler:        c.unmarshaler,
 		Namespace:                 nil,
 		Logger:                    c.logger,
+		Tracer:                    c.tracer,
+		Meter:                     c.meter,
 		DefaultMaxRetries:         c.maxRetries,
 	})
 }
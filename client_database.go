@@ -16,6 +16,8 @@ type httpDatabaseClient struct {
 	client      *httpqueryclient.Client
 	name        string
 	logger      Logger
+	tracer      RequestTracer
+	meter       Meter
 
 	defaultServerQueryTimeout time.Duration
 	defaultUnmarshaler        Unmarshaler
@@ -27,6 +29,8 @@ type httpDatabaseClientConfig struct {
 	Client      *httpqueryclient.Client
 	Name        string
 	Logger      Logger
+	Tracer      RequestTracer
+	Meter       Meter
 
 	DefaultServerTimeout time.Duration
 	DefaultUnmarshaler   Unmarshaler
@@ -41,6 +45,8 @@ func newHTTPDatabaseClient(cfg httpDatabaseClientConfig) *httpDatabaseClient {
 		defaultServerQueryTimeout: cfg.DefaultServerTimeout,
 		defaultUnmarshaler:        cfg.DefaultUnmarshaler,
 		logger:                    cfg.Logger,
+		tracer:                    cfg.Tracer,
+		meter:                     cfg.Meter,
 		defaultMaxRetries:         cfg.DefaultMaxRetries,
 	}
 }
@@ -56,6 +62,8 @@ func (c *httpDatabaseClient) Scope(name string) scopeClient {
 		DatabaseName: c.name,
 		Name:         name,
 		Logger:       c.logger,
+		Tracer:       c.tracer,
+		Meter:        c.meter,
 
 		DefaultServerQueryTimeout: c.defaultServerQueryTimeout,
 		DefaultUnmarshaler:        c.defaultUnmarshaler,
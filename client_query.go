@@ -17,10 +17,12 @@ import (
 type queryClient interface {
 	Query(ctx context.Context, statement string, opts *QueryOptions) (*QueryResult, error)
 	StartQuery(ctx context.Context, statement string, opts *StartQueryOptions) (*QueryHandle, error)
+	QueryHandleFromToken(token string) (*QueryHandle, error)
+	ResultHandleFromToken(token string) (*QueryResultHandle, error)
 }
 
 type queryHandleProvider interface {
-	fetchHandleStatus(ctx context.Context, handle string) (*QueryStatus, error)
+	fetchHandleStatus(ctx context.Context, handle string, endpoint string) (*QueryStatus, error)
 	discardHandleResults(ctx context.Context, handle string) error
 	cancelHandle(ctx context.Context, requestID string) error
 	streamHandleResults(ctx context.Context, handle string, unmarshaler Unmarshaler) (*QueryResult, error)
@@ -35,6 +37,8 @@ type httpQueryClient struct {
 	client      *httpqueryclient.Client
 	namespace   *queryClientNamespace
 	logger      Logger
+	tracer      RequestTracer
+	meter       Meter
 
 	defaultServerQueryTimeout time.Duration
 	defaultUnmarshaler        Unmarshaler
@@ -46,6 +50,8 @@ type httpQueryClientConfig struct {
 	Client      *httpqueryclient.Client
 	Namespace   *queryClientNamespace
 	Logger      Logger
+	Tracer      RequestTracer
+	Meter       Meter
 
 	DefaultServerQueryTimeout time.Duration
 	DefaultUnmarshaler        Unmarshaler
@@ -58,6 +64,8 @@ func newHTTPQueryClient(cfg httpQueryClientConfig) *httpQueryClient {
 		client:      cfg.Client,
 		namespace:   cfg.Namespace,
 		logger:      cfg.Logger,
+		tracer:      cfg.Tracer,
+		meter:       cfg.Meter,
 
 		defaultServerQueryTimeout: cfg.DefaultServerQueryTimeout,
 		defaultUnmarshaler:        cfg.DefaultUnmarshaler,
@@ -65,7 +73,22 @@ func newHTTPQueryClient(cfg httpQueryClientConfig) *httpQueryClient {
 	}
 }
 
-func (c *httpQueryClient) Query(ctx context.Context, statement string, opts *QueryOptions) (*QueryResult, error) {
+func (c *httpQueryClient) Query(ctx context.Context, statement string, opts *QueryOptions) (_ *QueryResult, err error) {
+	ctx, span := startOperationSpan(ctx, c.tracer, SpanNameExecuteQuery)
+	defer span.End()
+
+	ctx, metrics := startOperationMetrics(ctx, c.meter, c.logger, SpanNameExecuteQuery)
+	defer func() {
+		// On success the metrics are recorded once the result stream has been consumed.
+		if err != nil {
+			metrics.finishWithRows(err, 0)
+		}
+	}()
+
+	if redacted := c.client.RedactStatement(statement); redacted != "" {
+		span.SetAttribute(SpanAttributeStatement, redacted)
+	}
+
 	clientOpts, err := c.translateQueryOptions(ctx, statement, opts)
 	if err != nil {
 		return nil, err
@@ -81,7 +104,8 @@ func (c *httpQueryClient) Query(ctx context.Context, statement string, opts *Que
 		clientContextID = &id
 	}
 
-	clientOpts.Payload["client_context_id"] = clientContextID
+	clientOpts.Payload["client_context_id"] = *clientContextID
+	span.SetAttribute(SpanAttributeClientContextID, *clientContextID)
 
 	res, err := c.client.Query(ctx, clientOpts)
 	if err != nil {
@@ -94,8 +118,9 @@ func (c *httpQueryClient) Query(ctx context.Context, statement string, opts *Que
 	}
 
 	return &QueryResult{
-		reader:      c.newRowReader(res),
+		reader:      c.newRowReader(res, metrics),
 		unmarshaler: unmarshaler,
+		closed:      false,
 	}, nil
 }
 
@@ -139,6 +164,30 @@ func (c *httpQueryClient) translateQueryOptions(ctx context.Context, statement s
 		execOpts["readonly"] = *opts.ReadOnly
 	}
 
+	if planOpts := opts.PlanOptions; planOpts != nil {
+		if planOpts.Format != nil {
+			switch *planOpts.Format {
+			case QueryPlanFormatJSON:
+				execOpts["plan-format"] = "JSON"
+			case QueryPlanFormatString:
+				execOpts["plan-format"] = "STRING"
+			default:
+				return nil, invalidArgumentError{
+					ArgumentName: "PlanOptions.Format",
+					Reason:       "unknown value",
+				}
+			}
+		}
+
+		if planOpts.LogicalPlan != nil {
+			execOpts["logical-plan"] = *planOpts.LogicalPlan
+		}
+
+		if planOpts.OptimizedLogicalPlan != nil {
+			execOpts["optimized-logical-plan"] = *planOpts.OptimizedLogicalPlan
+		}
+	}
+
 	deadline, ok := ctx.Deadline()
 	if ok {
 		execOpts["timeout"] = (time.Until(deadline) + 5*time.Second).String()
@@ -154,24 +203,46 @@ func (c *httpQueryClient) translateQueryOptions(ctx context.Context, statement s
 	}
 
 	return &httpqueryclient.QueryOptions{
-		Payload:     execOpts,
-		AuthHandler: c.handleAuthHandler(),
-		MaxRetries:  maxRetries,
+		Payload:       execOpts,
+		AuthHandler:   c.handleAuthHandler(),
+		MaxRetries:    maxRetries,
+		RetryStrategy: newRetryStrategyWrapper(opts.RetryStrategy),
+		CancelOnClose: opts.CancelOnClose != nil && *opts.CancelOnClose,
 	}, nil
 }
 
 type clientRowReader struct {
 	reader *httpqueryclient.QueryRowReader
+
+	metrics *operationMetrics
+	rows    uint64
+	done    bool
 }
 
-func (c *httpQueryClient) newRowReader(result *httpqueryclient.QueryRowReader) *clientRowReader {
+func (c *httpQueryClient) newRowReader(result *httpqueryclient.QueryRowReader,
+	metrics *operationMetrics) *clientRowReader {
 	return &clientRowReader{
-		reader: result,
+		reader:  result,
+		metrics: metrics,
+		rows:    0,
+		done:    false,
 	}
 }
 
 func (c *clientRowReader) NextRow() []byte {
-	return c.reader.NextRow()
+	row := c.reader.NextRow()
+	if row != nil {
+		c.rows++
+
+		return row
+	}
+
+	if !c.done {
+		c.done = true
+		c.metrics.finishWithRows(c.Err(), c.rows)
+	}
+
+	return nil
 }
 
 func (c *clientRowReader) MetaData() (*QueryMetadata, error) {
@@ -196,14 +267,25 @@ func (c *clientRowReader) MetaData() (*QueryMetadata, error) {
 			ResultSize:       0,
 			ProcessedObjects: 0,
 		},
-		Warnings: nil,
+		Warnings:  nil,
+		QueueTime: c.reader.QueueTime(),
+		Plans:     nil,
 	}
 	meta.fromData(jsonResp)
 
 	return meta, nil
 }
 
+func (c *clientRowReader) Signature() json.RawMessage {
+	return c.reader.Signature()
+}
+
 func (c *clientRowReader) Close() error {
+	if !c.done {
+		c.done = true
+		c.metrics.finishWithRows(nil, c.rows)
+	}
+
 	err := c.reader.Close()
 	if err != nil {
 		return translateClientError(err)
@@ -237,6 +319,10 @@ func translateClientError(err error) error {
 			baseErr = ErrServiceUnavailable
 		case errors.Is(err, httpqueryclient.ErrTimeout):
 			baseErr = ErrTimeout
+		case errors.Is(err, httpqueryclient.ErrQueueFull):
+			baseErr = ErrQueueFull
+		case errors.Is(err, httpqueryclient.ErrCircuitOpen):
+			baseErr = ErrCircuitOpen
 		case errors.Is(err, context.Canceled):
 			baseErr = context.Canceled
 		case errors.Is(err, context.DeadlineExceeded):
@@ -244,7 +330,8 @@ func translateClientError(err error) error {
 		}
 
 		return newAnalyticsError(baseErr, clientErr.Statement, clientErr.Endpoint, clientErr.HTTPResponseCode, clientErr.Retries).
-			withMessage(clientErr.InnerError.Error())
+			withMessage(clientErr.InnerError.Error()).
+			withRequestIDs(clientErr.ClientContextID, clientErr.RequestID)
 	}
 
 	var firstNonRetriableErr *analyticsErrorDesc
@@ -252,8 +339,9 @@ func translateClientError(err error) error {
 	descs := make([]analyticsErrorDesc, len(clientErr.Errors))
 	for i, desc := range clientErr.Errors {
 		descs[i] = analyticsErrorDesc{
-			Code:    desc.Code,
-			Message: desc.Message,
+			Code:      desc.Code,
+			Message:   desc.Message,
+			Retriable: desc.Retry,
 		}
 
 		if firstNonRetriableErr == nil && !desc.Retry {
@@ -273,44 +361,10 @@ func translateClientError(err error) error {
 		msg = firstNonRetriableErr.Message
 	}
 
-	switch code {
-	case 20000:
-		return newQueryError(
-			ErrInvalidCredential,
-			clientErr.Statement,
-			clientErr.Endpoint,
-			clientErr.HTTPResponseCode,
-			code,
-			msg,
-			clientErr.Retries,
-		).
-			withErrors(descs)
-	case 21002:
-		return newQueryError(
-			ErrTimeout,
-			clientErr.Statement,
-			clientErr.Endpoint,
-			clientErr.HTTPResponseCode,
-			code,
-			msg,
-			clientErr.Retries,
-		).
-			withErrors(descs)
-	case 23000:
-		return newQueryError(
-			ErrServiceUnavailable,
-			clientErr.Statement,
-			clientErr.Endpoint,
-			clientErr.HTTPResponseCode,
-			code,
-			msg,
-			clientErr.Retries,
-		).
-			withErrors(descs)
-	}
+	cause := errorForCode(code)
 
 	qErr := newQueryError(
-		nil,
+		cause,
 		clientErr.Statement,
 		clientErr.Endpoint,
 		clientErr.HTTPResponseCode,
@@ -319,6 +373,13 @@ func translateClientError(err error) error {
 		clientErr.Retries,
 	).
 		withErrors(descs)
+	qErr.cause = qErr.cause.withRequestIDs(clientErr.ClientContextID, clientErr.RequestID)
+
+	// An invalid credential or missing permission is more specific than the request having timed out or been
+	// canceled.
+	if errors.Is(cause, ErrInvalidCredential) || errors.Is(cause, ErrPermissionDenied) {
+		return qErr
+	}
 
 	switch {
 	case errors.Is(clientErr.InnerError, httpqueryclient.ErrTimeout):
@@ -347,7 +408,20 @@ func (c *httpQueryClient) handleAuthHandler() func(req *http.Request) {
 	}
 }
 
-func (c *httpQueryClient) StartQuery(ctx context.Context, statement string, opts *StartQueryOptions) (*QueryHandle, error) {
+func (c *httpQueryClient) StartQuery(ctx context.Context, statement string,
+	opts *StartQueryOptions) (_ *QueryHandle, err error) {
+	ctx, span := startOperationSpan(ctx, c.tracer, SpanNameStartQuery)
+	defer span.End()
+
+	ctx, metrics := startOperationMetrics(ctx, c.meter, c.logger, SpanNameStartQuery)
+	defer func() {
+		metrics.finish(err)
+	}()
+
+	if redacted := c.client.RedactStatement(statement); redacted != "" {
+		span.SetAttribute(SpanAttributeStatement, redacted)
+	}
+
 	clientOpts, err := c.translateStartQueryOptions(ctx, statement, opts)
 	if err != nil {
 		return nil, err
@@ -363,7 +437,8 @@ func (c *httpQueryClient) StartQuery(ctx context.Context, statement string, opts
 		clientContextID = &id
 	}
 
-	clientOpts.Payload["client_context_id"] = clientContextID
+	clientOpts.Payload["client_context_id"] = *clientContextID
+	span.SetAttribute(SpanAttributeClientContextID, *clientContextID)
 	clientOpts.Payload["mode"] = "async"
 
 	res, err := c.client.Query(ctx, clientOpts)
@@ -388,22 +463,80 @@ func (c *httpQueryClient) StartQuery(ctx context.Context, statement string, opts
 	}
 
 	if jsonResp.Handle == "" {
-		return nil, newAnalyticsError(ErrAnalytics, statement, c.client.Host(), 0, 0).
+		return nil, newAnalyticsError(ErrAnalytics, c.client.RedactStatement(statement), res.Endpoint(), 0, 0).
 			withMessage("async query response did not contain a handle")
 	}
 
 	if jsonResp.RequestID == "" {
-		return nil, newAnalyticsError(ErrAnalytics, statement, c.client.Host(), 0, 0).
+		return nil, newAnalyticsError(ErrAnalytics, c.client.RedactStatement(statement), res.Endpoint(), 0, 0).
 			withMessage("async query response did not contain a request id")
 	}
 
+	span.SetAttribute(SpanAttributeRequestID, jsonResp.RequestID)
+
 	return &QueryHandle{
 		handle:    jsonResp.Handle,
 		requestID: jsonResp.RequestID,
+		endpoint:  res.Endpoint(),
 		provider:  c,
 	}, nil
 }
 
+func (c *httpQueryClient) QueryHandleFromToken(token string) (*QueryHandle, error) {
+	parsed, err := c.parseHandleToken(token, handleTokenKindQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueryHandle{
+		handle:    parsed.Handle,
+		requestID: parsed.RequestID,
+		endpoint:  parsed.Endpoint,
+		provider:  c,
+	}, nil
+}
+
+func (c *httpQueryClient) ResultHandleFromToken(token string) (*QueryResultHandle, error) {
+	parsed, err := c.parseHandleToken(token, handleTokenKindResult)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueryResultHandle{
+		handle:      parsed.Handle,
+		endpoint:    parsed.Endpoint,
+		provider:    c,
+		unmarshaler: c.defaultUnmarshaler,
+	}, nil
+}
+
+// parseHandleToken decodes a handle token, checking that it is of the expected kind and that it was issued
+// by one of the endpoints that this client is configured with.
+func (c *httpQueryClient) parseHandleToken(token string, kind string) (*jsonHandleToken, error) {
+	parsed, err := decodeHandleToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsed.Kind != kind {
+		return nil, invalidArgumentError{
+			ArgumentName: "token",
+			Reason:       fmt.Sprintf("expected a %s handle token but got a %s handle token", kind, parsed.Kind),
+		}
+	}
+
+	for _, endpoint := range c.client.Endpoints() {
+		if endpoint.String() == parsed.Endpoint {
+			return parsed, nil
+		}
+	}
+
+	return nil, invalidArgumentError{
+		ArgumentName: "token",
+		Reason:       fmt.Sprintf("handle was issued by %s which is not an endpoint of this cluster", parsed.Endpoint),
+	}
+}
+
 func (c *httpQueryClient) translateStartQueryOptions(ctx context.Context, statement string,
 	opts *StartQueryOptions) (*httpqueryclient.QueryOptions, error) {
 	execOpts := make(map[string]interface{})
@@ -461,9 +594,10 @@ func (c *httpQueryClient) translateStartQueryOptions(ctx context.Context, statem
 	}
 
 	return &httpqueryclient.QueryOptions{
-		Payload:     execOpts,
-		AuthHandler: c.handleAuthHandler(),
-		MaxRetries:  maxRetries,
+		Payload:       execOpts,
+		AuthHandler:   c.handleAuthHandler(),
+		MaxRetries:    maxRetries,
+		RetryStrategy: newRetryStrategyWrapper(opts.RetryStrategy),
 	}, nil
 }
 
@@ -474,10 +608,11 @@ type jsonHandleStatusError struct {
 }
 
 type jsonHandleStatusResponse struct {
-	Status  string                  `json:"status"`
-	Handle  string                  `json:"handle,omitempty"`
-	Errors  []jsonHandleStatusError `json:"errors,omitempty"`
-	Metrics json.RawMessage         `json:"metrics,omitempty"`
+	Status    string                  `json:"status"`
+	Handle    string                  `json:"handle,omitempty"`
+	CreatedAt json.RawMessage         `json:"createdAt,omitempty"`
+	Errors    []jsonHandleStatusError `json:"errors,omitempty"`
+	Metrics   json.RawMessage         `json:"metrics,omitempty"`
 }
 
 func (c *httpQueryClient) translateHandleError(err error) error {
@@ -503,49 +638,72 @@ func (c *httpQueryClient) translateHandleError(err error) error {
 	}
 }
 
-func (c *httpQueryClient) fetchHandleStatus(ctx context.Context, handle string) (*QueryStatus, error) {
-	respBody, err := c.client.FetchHandleStatus(ctx, handle, c.handleAuthHandler(), c.defaultMaxRetries)
+// fetchHandleStatus fetches the status of a deferred query. Errors report endpoint, the endpoint that issued the
+// handle.
+func (c *httpQueryClient) fetchHandleStatus(ctx context.Context, handle string,
+	endpoint string) (_ *QueryStatus, err error) {
+	ctx, span := startOperationSpan(ctx, c.tracer, SpanNameFetchStatus)
+	defer span.End()
+
+	ctx, opMetrics := startOperationMetrics(ctx, c.meter, c.logger, SpanNameFetchStatus)
+	defer func() {
+		opMetrics.finish(err)
+	}()
+
+	respBody, respEndpoint, err := c.client.FetchHandleStatus(ctx, handle, c.handleAuthHandler(), c.defaultMaxRetries)
 	if err != nil {
 		return nil, c.translateHandleError(err)
 	}
 
 	var statusResp jsonHandleStatusResponse
 	if err := json.Unmarshal(respBody, &statusResp); err != nil {
-		return nil, newAnalyticsError(ErrAnalytics, "", c.client.Host(), 0, 0).
+		return nil, newAnalyticsError(ErrAnalytics, "", endpoint, 0, 0).
 			withMessage("failed to parse handle status response")
 	}
 
 	if len(statusResp.Errors) > 0 {
-		return nil, c.buildHandleStatusError(&statusResp)
+		return nil, c.buildHandleStatusError(&statusResp, handle, endpoint)
+	}
+
+	status := &QueryStatus{
+		status:    "",
+		metrics:   "",
+		createdAt: time.Time{},
+		parsedMetrics: QueryMetrics{
+			ElapsedTime:      0,
+			ExecutionTime:    0,
+			ResultCount:      0,
+			ResultSize:       0,
+			ProcessedObjects: 0,
+		},
+		resultHandle: nil,
+		err:          nil,
 	}
+	status.fromData(statusResp)
 
-	metrics := string(statusResp.Metrics)
+	if status.State().isFailed() {
+		status.err = c.buildHandleStatusError(&statusResp, handle, endpoint)
+	}
 
-	if statusResp.Status == "success" {
-		return &QueryStatus{
-			status:  statusResp.Status,
-			metrics: metrics,
-			resultHandle: &QueryResultHandle{
-				handle:      statusResp.Handle,
-				provider:    c,
-				unmarshaler: c.defaultUnmarshaler,
-			},
-		}, nil
+	if status.State() == QueryStatusStateSuccess {
+		status.resultHandle = &QueryResultHandle{
+			handle:      statusResp.Handle,
+			endpoint:    respEndpoint,
+			provider:    c,
+			unmarshaler: c.defaultUnmarshaler,
+		}
 	}
 
-	return &QueryStatus{
-		resultHandle: nil,
-		status:       statusResp.Status,
-		metrics:      metrics,
-	}, nil
+	return status, nil
 }
 
-func (c *httpQueryClient) buildHandleStatusError(statusResp *jsonHandleStatusResponse) error {
-	endpoint := c.client.Host()
-
+// buildHandleStatusError returns the error for a deferred query which has ended without results. If the server
+// reported errors then a QueryError is returned, otherwise an AnalyticsError naming the handle and status.
+func (c *httpQueryClient) buildHandleStatusError(statusResp *jsonHandleStatusResponse, handle string,
+	endpoint string) error {
 	if len(statusResp.Errors) == 0 {
 		return newAnalyticsError(ErrAnalytics, "", endpoint, 0, 0).
-			withMessage(fmt.Sprintf("query ended with status: %s", statusResp.Status))
+			withMessage(fmt.Sprintf("query %s ended with status: %s", handle, statusResp.Status))
 	}
 
 	descs := make([]analyticsErrorDesc, len(statusResp.Errors))
@@ -554,8 +712,9 @@ func (c *httpQueryClient) buildHandleStatusError(statusResp *jsonHandleStatusRes
 
 	for i, e := range statusResp.Errors {
 		descs[i] = analyticsErrorDesc{
-			Code:    e.Code,
-			Message: e.Message,
+			Code:      e.Code,
+			Message:   e.Message,
+			Retriable: e.Retry,
 		}
 
 		if firstNonRetriable == nil && !e.Retry {
@@ -575,24 +734,19 @@ func (c *httpQueryClient) buildHandleStatusError(statusResp *jsonHandleStatusRes
 		msg = firstNonRetriable.Message
 	}
 
-	var cause error
-
-	switch code {
-	case 20000:
-		cause = ErrInvalidCredential
-	case 21002:
-		cause = ErrTimeout
-	case 23000:
-		cause = ErrServiceUnavailable
-	default:
-		cause = ErrQuery
-	}
-
-	return newQueryError(cause, "", endpoint, 0, code, msg, 0).
+	return newQueryError(errorForCode(code), "", endpoint, 0, code, msg, 0).
 		withErrors(descs)
 }
 
-func (c *httpQueryClient) discardHandleResults(ctx context.Context, handle string) error {
+func (c *httpQueryClient) discardHandleResults(ctx context.Context, handle string) (err error) {
+	ctx, span := startOperationSpan(ctx, c.tracer, SpanNameDiscardResults)
+	defer span.End()
+
+	ctx, metrics := startOperationMetrics(ctx, c.meter, c.logger, SpanNameDiscardResults)
+	defer func() {
+		metrics.finish(err)
+	}()
+
 	if err := c.client.DiscardHandleResults(ctx, handle, c.handleAuthHandler(), c.defaultMaxRetries); err != nil {
 		return c.translateHandleError(err)
 	}
@@ -600,7 +754,17 @@ func (c *httpQueryClient) discardHandleResults(ctx context.Context, handle strin
 	return nil
 }
 
-func (c *httpQueryClient) cancelHandle(ctx context.Context, requestID string) error {
+func (c *httpQueryClient) cancelHandle(ctx context.Context, requestID string) (err error) {
+	ctx, span := startOperationSpan(ctx, c.tracer, SpanNameCancel)
+	defer span.End()
+
+	ctx, metrics := startOperationMetrics(ctx, c.meter, c.logger, SpanNameCancel)
+	defer func() {
+		metrics.finish(err)
+	}()
+
+	span.SetAttribute(SpanAttributeRequestID, requestID)
+
 	if err := c.client.CancelHandle(ctx, requestID, c.handleAuthHandler(), c.defaultMaxRetries); err != nil {
 		return c.translateHandleError(err)
 	}
@@ -609,7 +773,18 @@ func (c *httpQueryClient) cancelHandle(ctx context.Context, requestID string) er
 }
 
 func (c *httpQueryClient) streamHandleResults(ctx context.Context, handle string,
-	unmarshaler Unmarshaler) (*QueryResult, error) {
+	unmarshaler Unmarshaler) (_ *QueryResult, err error) {
+	ctx, span := startOperationSpan(ctx, c.tracer, SpanNameFetchResults)
+	defer span.End()
+
+	ctx, metrics := startOperationMetrics(ctx, c.meter, c.logger, SpanNameFetchResults)
+	defer func() {
+		// On success the metrics are recorded once the result stream has been consumed.
+		if err != nil {
+			metrics.finishWithRows(err, 0)
+		}
+	}()
+
 	res, err := c.client.StreamHandleResults(ctx, handle, c.handleAuthHandler(), c.defaultMaxRetries)
 	if err != nil {
 		return nil, c.translateHandleError(err)
@@ -620,7 +795,8 @@ func (c *httpQueryClient) streamHandleResults(ctx context.Context, handle string
 	}
 
 	return &QueryResult{
-		reader:      c.newRowReader(res),
+		reader:      c.newRowReader(res, metrics),
 		unmarshaler: unmarshaler,
+		closed:      false,
 	}, nil
 }
@@ -17,6 +17,8 @@ type httpScopeClient struct {
 	name         string
 	databaseName string
 	logger       Logger
+	tracer       RequestTracer
+	meter        Meter
 
 	defaultServerQueryTimeout time.Duration
 	defaultUnmarshaler        Unmarshaler
@@ -29,6 +31,8 @@ type httpScopeClientConfig struct {
 	DatabaseName string
 	Name         string
 	Logger       Logger
+	Tracer       RequestTracer
+	Meter        Meter
 
 	DefaultServerQueryTimeout time.Duration
 	DefaultUnmarshaler        Unmarshaler
@@ -42,6 +46,8 @@ func newHTTPScopeClient(cfg httpScopeClientConfig) *httpScopeClient {
 		name:         cfg.Name,
 		databaseName: cfg.DatabaseName,
 		logger:       cfg.Logger,
+		tracer:       cfg.Tracer,
+		meter:        cfg.Meter,
 
 		defaultServerQueryTimeout: cfg.DefaultServerQueryTimeout,
 		defaultUnmarshaler:        cfg.DefaultUnmarshaler,
@@ -62,6 +68,8 @@ func (c *httpScopeClient) QueryClient() queryClient {
 			Scope:    c.name,
 		},
 		Logger: c.logger,
+		Tracer: c.tracer,
+		Meter:  c.meter,
 
 		DefaultServerQueryTimeout: c.defaultServerQueryTimeout,
 		DefaultUnmarshaler:        c.defaultUnmarshaler,
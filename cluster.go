@@ -6,9 +6,14 @@
 package cbanalytics
 
 import (
+	"fmt"
+	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/couchbase/gocbanalytics/internal/httpqueryclient"
 )
 
 // Cluster is the main entry point for the SDK.
@@ -18,42 +23,14 @@ type Cluster struct {
 }
 
 // NewCluster creates a new Cluster instance.
+// The httpEndpoint may contain a comma separated list of hosts, e.g. "https://host1,host2:8095", in which
+// case requests are spread across the hosts and retries fail over to other hosts when one becomes unavailable.
 func NewCluster(httpEndpoint string, credential Credential, opts ...*ClusterOptions) (*Cluster, error) {
 	// This is leaking implementation detail of the client abstraction a little bit, but it's ok.
 	// There's no point in overcomplicating this for the sake of perfection.
-	connSpec, err := url.Parse(httpEndpoint)
+	connSpec, addrs, err := parseConnectionString(httpEndpoint)
 	if err != nil {
-		return nil, err //nolint:wrapcheck
-	}
-
-	if connSpec.Scheme != "https" && connSpec.Scheme != "http" {
-		return nil, invalidArgumentError{
-			ArgumentName: "scheme",
-			Reason:       "only http and https schemes are supported",
-		}
-	}
-
-	var port int
-
-	if connSpec.Port() == "" {
-		switch connSpec.Scheme {
-		case "https":
-			port = 443
-		case "http":
-			port = 80
-		}
-	} else {
-		thisPort, err := strconv.Atoi(connSpec.Port())
-		if err != nil {
-			return nil, err //nolint:wrapcheck
-		}
-
-		port = thisPort
-	}
-
-	addr := address{
-		Host: connSpec.Hostname(),
-		Port: port,
+		return nil, err
 	}
 
 	if credential == nil {
@@ -208,6 +185,98 @@ func NewCluster(httpEndpoint string, credential Credential, opts ...*ClusterOpti
 		maxRetries = uint32(m)
 	}
 
+	retryStrategy := clusterOpts.RetryStrategy
+	if retryStrategy == nil {
+		retryStrategy = newDefaultRetryStrategy()
+	}
+
+	tracer := clusterOpts.Tracer
+	if tracer == nil {
+		tracer = NewNoopTracer()
+	}
+
+	meter := clusterOpts.Meter
+	if meter == nil {
+		meter = NewNoopMeter()
+	}
+
+	transportConfig, err := newTransportConfig(clusterOpts.TransportOptions, fetchOption)
+	if err != nil {
+		return nil, err
+	}
+
+	limiterConfig := httpqueryclient.LimiterConfig{
+		MaxInFlight: 0,
+		MaxQueued:   -1,
+	}
+
+	if concurrencyOpts := clusterOpts.ConcurrencyLimitOptions; concurrencyOpts != nil {
+		if concurrencyOpts.MaxInFlight != nil {
+			limiterConfig.MaxInFlight = int(*concurrencyOpts.MaxInFlight)
+		}
+
+		if concurrencyOpts.MaxQueueLength != nil {
+			limiterConfig.MaxQueued = int(*concurrencyOpts.MaxQueueLength)
+		}
+	}
+
+	if valStr, ok := fetchOption("concurrency.max_in_flight"); ok {
+		m, err := strconv.ParseUint(valStr, 10, 32)
+		if err != nil {
+			return nil, invalidArgumentError{
+				ArgumentName: "concurrency.max_in_flight",
+				Reason:       err.Error(),
+			}
+		}
+
+		limiterConfig.MaxInFlight = int(m)
+	}
+
+	if valStr, ok := fetchOption("concurrency.max_queue_length"); ok {
+		m, err := strconv.ParseUint(valStr, 10, 32)
+		if err != nil {
+			return nil, invalidArgumentError{
+				ArgumentName: "concurrency.max_queue_length",
+				Reason:       err.Error(),
+			}
+		}
+
+		limiterConfig.MaxQueued = int(m)
+	}
+
+	retryBudgetConfig, err := newRetryBudgetConfig(clusterOpts.RetryBudgetOptions, fetchOption)
+	if err != nil {
+		return nil, err
+	}
+
+	circuitBreakerConfig, err := newCircuitBreakerConfig(clusterOpts.CircuitBreakerOptions, fetchOption)
+	if err != nil {
+		return nil, err
+	}
+
+	redaction, err := newRedactionLevel(clusterOpts.RedactionPolicy, fetchOption)
+	if err != nil {
+		return nil, err
+	}
+
+	recordReplayOpts := clusterOpts.RecordReplayOptions
+	if recordReplayOpts != nil {
+		if recordReplayOpts.Mode == nil ||
+			(*recordReplayOpts.Mode != RecordReplayModeRecord && *recordReplayOpts.Mode != RecordReplayModeReplay) {
+			return nil, invalidArgumentError{
+				ArgumentName: "RecordReplayOptions.Mode",
+				Reason:       "must be RecordReplayModeRecord or RecordReplayModeReplay",
+			}
+		}
+
+		if recordReplayOpts.Directory == nil || *recordReplayOpts.Directory == "" {
+			return nil, invalidArgumentError{
+				ArgumentName: "RecordReplayOptions.Directory",
+				Reason:       "must be specified",
+			}
+		}
+	}
+
 	mgr, err := newClusterClient(clusterClientOptions{
 		Scheme:                               connSpec.Scheme,
 		Credential:                           credential,
@@ -215,10 +284,20 @@ func NewCluster(httpEndpoint string, credential Credential, opts ...*ClusterOpti
 		ServerQueryTimeout:                   queryTimeout,
 		TrustOnly:                            securityOpts.TrustOnly,
 		DisableServerCertificateVerification: securityOpts.DisableServerCertificateVerification,
-		Address:                              addr,
+		Addresses:                            addrs,
 		Unmarshaler:                          unmarshaler,
 		Logger:                               logger,
 		MaxRetries:                           maxRetries,
+		RetryStrategy:                        retryStrategy,
+		Tracer:                               tracer,
+		Meter:                                meter,
+		RecordReplay:                         recordReplayOpts,
+		WrapTransport:                        clusterOpts.WrapTransport,
+		Transport:                            transportConfig,
+		Limiter:                              limiterConfig,
+		RetryBudget:                          retryBudgetConfig,
+		CircuitBreaker:                       circuitBreakerConfig,
+		Redaction:                            redaction,
 	})
 	if err != nil {
 		return nil, err
@@ -253,3 +332,450 @@ func (c *Cluster) SetCredential(credential Credential) error {
 func (c *Cluster) Close() error {
 	return c.client.Close() //nolint:wrapcheck
 }
+
+// newTransportConfig applies defaults to the transport options, and overrides them with any transport options
+// from the connection string.
+func newTransportConfig(opts *TransportOptions,
+	fetchOption func(name string) (string, bool)) (httpqueryclient.TransportConfig, error) {
+	config := httpqueryclient.TransportConfig{
+		MaxIdleConnsPerHost: 0,
+		IdleConnTimeout:     1000 * time.Millisecond,
+		MaxConnsPerHost:     0,
+		DisableHTTP2:        false,
+		ReadBufferSize:      0,
+		WriteBufferSize:     0,
+		TCPKeepAlive:        30 * time.Second,
+		Proxy:               nil,
+	}
+
+	if opts == nil {
+		opts = NewTransportOptions()
+	}
+
+	if opts.MaxIdleConnsPerHost != nil {
+		config.MaxIdleConnsPerHost = *opts.MaxIdleConnsPerHost
+	}
+
+	if opts.IdleConnTimeout != nil {
+		config.IdleConnTimeout = *opts.IdleConnTimeout
+	}
+
+	if opts.MaxConnsPerHost != nil {
+		config.MaxConnsPerHost = *opts.MaxConnsPerHost
+	}
+
+	if opts.DisableHTTP2 != nil {
+		config.DisableHTTP2 = *opts.DisableHTTP2
+	}
+
+	if opts.ReadBufferSize != nil {
+		config.ReadBufferSize = *opts.ReadBufferSize
+	}
+
+	if opts.WriteBufferSize != nil {
+		config.WriteBufferSize = *opts.WriteBufferSize
+	}
+
+	if opts.TCPKeepAlive != nil {
+		config.TCPKeepAlive = *opts.TCPKeepAlive
+	}
+
+	intOptions := map[string]*int{
+		"transport.max_idle_conns_per_host": &config.MaxIdleConnsPerHost,
+		"transport.max_conns_per_host":      &config.MaxConnsPerHost,
+		"transport.read_buffer_size":        &config.ReadBufferSize,
+		"transport.write_buffer_size":       &config.WriteBufferSize,
+	}
+
+	for name, val := range intOptions {
+		if valStr, ok := fetchOption(name); ok {
+			parsed, err := strconv.Atoi(valStr)
+			if err != nil {
+				return httpqueryclient.TransportConfig{}, invalidArgumentError{
+					ArgumentName: name,
+					Reason:       err.Error(),
+				}
+			}
+
+			*val = parsed
+		}
+	}
+
+	durationOptions := map[string]*time.Duration{
+		"transport.idle_conn_timeout": &config.IdleConnTimeout,
+		"transport.tcp_keepalive":     &config.TCPKeepAlive,
+	}
+
+	for name, val := range durationOptions {
+		if valStr, ok := fetchOption(name); ok {
+			parsed, err := time.ParseDuration(valStr)
+			if err != nil {
+				return httpqueryclient.TransportConfig{}, invalidArgumentError{
+					ArgumentName: name,
+					Reason:       err.Error(),
+				}
+			}
+
+			*val = parsed
+		}
+	}
+
+	if valStr, ok := fetchOption("transport.disable_http2"); ok {
+		val, err := strconv.ParseBool(valStr)
+		if err != nil {
+			return httpqueryclient.TransportConfig{}, invalidArgumentError{
+				ArgumentName: "transport.disable_http2",
+				Reason:       err.Error(),
+			}
+		}
+
+		config.DisableHTTP2 = val
+	}
+
+	var proxyURL, noProxy string
+
+	if opts.ProxyURL != nil {
+		proxyURL = *opts.ProxyURL
+	}
+
+	if opts.NoProxy != nil {
+		noProxy = *opts.NoProxy
+	}
+
+	proxyFromEnvironment := opts.ProxyFromEnvironment != nil && *opts.ProxyFromEnvironment
+
+	if valStr, ok := fetchOption("transport.proxy_url"); ok {
+		proxyURL = valStr
+	}
+
+	if valStr, ok := fetchOption("transport.no_proxy"); ok {
+		noProxy = valStr
+	}
+
+	if valStr, ok := fetchOption("transport.proxy_from_environment"); ok {
+		val, err := strconv.ParseBool(valStr)
+		if err != nil {
+			return httpqueryclient.TransportConfig{}, invalidArgumentError{
+				ArgumentName: "transport.proxy_from_environment",
+				Reason:       err.Error(),
+			}
+		}
+
+		proxyFromEnvironment = val
+	}
+
+	if proxyURL != "" {
+		parsed, err := url.Parse(proxyURL)
+		if err != nil {
+			// The error is not included as it contains the URL, which may contain credentials.
+			return httpqueryclient.TransportConfig{}, invalidArgumentError{
+				ArgumentName: "ProxyURL",
+				Reason:       "must be a valid url",
+			}
+		}
+
+		switch parsed.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return httpqueryclient.TransportConfig{}, invalidArgumentError{
+				ArgumentName: "ProxyURL",
+				Reason:       "scheme must be http, https or socks5",
+			}
+		}
+
+		if parsed.Host == "" {
+			return httpqueryclient.TransportConfig{}, invalidArgumentError{
+				ArgumentName: "ProxyURL",
+				Reason:       "must contain a host",
+			}
+		}
+
+		config.Proxy = httpqueryclient.NewProxyFunc(parsed, noProxy)
+	} else if proxyFromEnvironment {
+		config.Proxy = http.ProxyFromEnvironment
+	}
+
+	nonNegative := []struct {
+		name string
+		val  int64
+	}{
+		{"MaxIdleConnsPerHost", int64(config.MaxIdleConnsPerHost)},
+		{"IdleConnTimeout", int64(config.IdleConnTimeout)},
+		{"MaxConnsPerHost", int64(config.MaxConnsPerHost)},
+		{"ReadBufferSize", int64(config.ReadBufferSize)},
+		{"WriteBufferSize", int64(config.WriteBufferSize)},
+	}
+
+	for _, opt := range nonNegative {
+		if opt.val < 0 {
+			return httpqueryclient.TransportConfig{}, invalidArgumentError{
+				ArgumentName: opt.name,
+				Reason:       "must not be negative",
+			}
+		}
+	}
+
+	return config, nil
+}
+
+// newRetryBudgetConfig creates the retry budget configuration from opts and the connection string. The budget is
+// enabled if opts is set, or if any retry_budget option is in the connection string.
+func newRetryBudgetConfig(opts *RetryBudgetOptions,
+	fetchOption func(name string) (string, bool)) (httpqueryclient.RetryBudgetConfig, error) {
+	config := httpqueryclient.RetryBudgetConfig{
+		Enabled:    opts != nil,
+		Ratio:      0.2,
+		MinRetries: 10,
+		Window:     10 * time.Second,
+	}
+
+	if opts == nil {
+		opts = NewRetryBudgetOptions()
+	}
+
+	if opts.Percent != nil {
+		config.Ratio = float64(*opts.Percent) / 100
+	}
+
+	if opts.MinRetries != nil {
+		config.MinRetries = int(*opts.MinRetries)
+	}
+
+	if opts.Window != nil {
+		config.Window = *opts.Window
+	}
+
+	if valStr, ok := fetchOption("retry_budget.percent"); ok {
+		val, err := strconv.ParseUint(valStr, 10, 32)
+		if err != nil {
+			return httpqueryclient.RetryBudgetConfig{}, invalidArgumentError{
+				ArgumentName: "retry_budget.percent",
+				Reason:       err.Error(),
+			}
+		}
+
+		config.Enabled = true
+		config.Ratio = float64(val) / 100
+	}
+
+	if valStr, ok := fetchOption("retry_budget.min_retries"); ok {
+		val, err := strconv.ParseUint(valStr, 10, 32)
+		if err != nil {
+			return httpqueryclient.RetryBudgetConfig{}, invalidArgumentError{
+				ArgumentName: "retry_budget.min_retries",
+				Reason:       err.Error(),
+			}
+		}
+
+		config.Enabled = true
+		config.MinRetries = int(val)
+	}
+
+	if valStr, ok := fetchOption("retry_budget.window"); ok {
+		val, err := time.ParseDuration(valStr)
+		if err != nil {
+			return httpqueryclient.RetryBudgetConfig{}, invalidArgumentError{
+				ArgumentName: "retry_budget.window",
+				Reason:       err.Error(),
+			}
+		}
+
+		config.Enabled = true
+		config.Window = val
+	}
+
+	if config.Window <= 0 {
+		return httpqueryclient.RetryBudgetConfig{}, invalidArgumentError{
+			ArgumentName: "RetryBudgetOptions.Window",
+			Reason:       "must be greater than 0",
+		}
+	}
+
+	return config, nil
+}
+
+// newCircuitBreakerConfig creates the circuit breaker configuration from opts and the connection string. Circuit
+// breakers are enabled if opts is set, or if any circuit_breaker option is in the connection string.
+func newCircuitBreakerConfig(opts *CircuitBreakerOptions,
+	fetchOption func(name string) (string, bool)) (httpqueryclient.CircuitBreakerConfig, error) {
+	config := httpqueryclient.CircuitBreakerConfig{
+		Enabled:          opts != nil,
+		FailureThreshold: 5,
+		CoolDown:         10 * time.Second,
+	}
+
+	if opts == nil {
+		opts = NewCircuitBreakerOptions()
+	}
+
+	if opts.FailureThreshold != nil {
+		config.FailureThreshold = int(*opts.FailureThreshold)
+	}
+
+	if opts.CoolDown != nil {
+		config.CoolDown = *opts.CoolDown
+	}
+
+	if valStr, ok := fetchOption("circuit_breaker.failure_threshold"); ok {
+		val, err := strconv.ParseUint(valStr, 10, 32)
+		if err != nil {
+			return httpqueryclient.CircuitBreakerConfig{}, invalidArgumentError{
+				ArgumentName: "circuit_breaker.failure_threshold",
+				Reason:       err.Error(),
+			}
+		}
+
+		config.Enabled = true
+		config.FailureThreshold = int(val)
+	}
+
+	if valStr, ok := fetchOption("circuit_breaker.cool_down"); ok {
+		val, err := time.ParseDuration(valStr)
+		if err != nil {
+			return httpqueryclient.CircuitBreakerConfig{}, invalidArgumentError{
+				ArgumentName: "circuit_breaker.cool_down",
+				Reason:       err.Error(),
+			}
+		}
+
+		config.Enabled = true
+		config.CoolDown = val
+	}
+
+	if config.FailureThreshold == 0 {
+		return httpqueryclient.CircuitBreakerConfig{}, invalidArgumentError{
+			ArgumentName: "CircuitBreakerOptions.FailureThreshold",
+			Reason:       "must be greater than 0",
+		}
+	}
+
+	if config.CoolDown <= 0 {
+		return httpqueryclient.CircuitBreakerConfig{}, invalidArgumentError{
+			ArgumentName: "CircuitBreakerOptions.CoolDown",
+			Reason:       "must be greater than 0",
+		}
+	}
+
+	return config, nil
+}
+
+// newRedactionLevel returns the redaction level for policy, or for the redaction_policy option in the connection
+// string if it is set.
+func newRedactionLevel(policy *RedactionPolicy,
+	fetchOption func(name string) (string, bool)) (httpqueryclient.RedactionLevel, error) {
+	level := httpqueryclient.RedactionNone
+
+	if policy != nil {
+		switch *policy {
+		case RedactionPolicyNone:
+		case RedactionPolicyPartial:
+			level = httpqueryclient.RedactionPartial
+		case RedactionPolicyFull:
+			level = httpqueryclient.RedactionFull
+		default:
+			return 0, invalidArgumentError{
+				ArgumentName: "RedactionPolicy",
+				Reason:       "must be RedactionPolicyNone, RedactionPolicyPartial or RedactionPolicyFull",
+			}
+		}
+	}
+
+	if valStr, ok := fetchOption("redaction_policy"); ok {
+		switch valStr {
+		case "none":
+			level = httpqueryclient.RedactionNone
+		case "partial":
+			level = httpqueryclient.RedactionPartial
+		case "full":
+			level = httpqueryclient.RedactionFull
+		default:
+			return 0, invalidArgumentError{
+				ArgumentName: "redaction_policy",
+				Reason:       "must be none, partial or full",
+			}
+		}
+	}
+
+	return level, nil
+}
+
+// parseConnectionString parses a connection string which may contain multiple comma separated hosts.
+// The returned URL contains only the first host, and is used for everything other than the hosts.
+func parseConnectionString(connStr string) (*url.URL, []address, error) {
+	var hostList string
+
+	schemeEnd := strings.Index(connStr, "://")
+	if schemeEnd >= 0 {
+		rest := connStr[schemeEnd+3:]
+
+		hostEnd := strings.IndexAny(rest, "/?#")
+		if hostEnd < 0 {
+			hostEnd = len(rest)
+		}
+
+		hostList = rest[:hostEnd]
+
+		if firstHost, _, found := strings.Cut(hostList, ","); found {
+			connStr = connStr[:schemeEnd+3] + firstHost + rest[hostEnd:]
+		}
+	}
+
+	connSpec, err := url.Parse(connStr)
+	if err != nil {
+		return nil, nil, err //nolint:wrapcheck
+	}
+
+	if connSpec.Scheme != "https" && connSpec.Scheme != "http" {
+		return nil, nil, invalidArgumentError{
+			ArgumentName: "scheme",
+			Reason:       "only http and https schemes are supported",
+		}
+	}
+
+	var defaultPort int
+
+	switch connSpec.Scheme {
+	case "https":
+		defaultPort = 443
+	case "http":
+		defaultPort = 80
+	}
+
+	hosts := strings.Split(hostList, ",")
+	addrs := make([]address, 0, len(hosts))
+
+	for _, host := range hosts {
+		hostURL, err := url.Parse(connSpec.Scheme + "://" + host)
+		if err != nil {
+			return nil, nil, invalidArgumentError{
+				ArgumentName: "httpEndpoint",
+				Reason:       fmt.Sprintf("invalid host %q: %s", host, err),
+			}
+		}
+
+		if hostURL.Hostname() == "" {
+			return nil, nil, invalidArgumentError{
+				ArgumentName: "httpEndpoint",
+				Reason:       "host cannot be empty",
+			}
+		}
+
+		port := defaultPort
+
+		if hostURL.Port() != "" {
+			thisPort, err := strconv.Atoi(hostURL.Port())
+			if err != nil {
+				return nil, nil, err //nolint:wrapcheck
+			}
+
+			port = thisPort
+		}
+
+		addrs = append(addrs, address{
+			Host: hostURL.Hostname(),
+			Port: port,
+		})
+	}
+
+	return connSpec, addrs, nil
+}
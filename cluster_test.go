@@ -54,3 +54,17 @@ func TestSetCredential_Nil(t *testing.T) {
 	err = cluster.SetCredential(nil)
 	assert.ErrorIs(t, err, cbanalytics.ErrInvalidArgument)
 }
+
+func TestMultipleHosts(t *testing.T) {
+	cluster, err := cbanalytics.NewCluster("http://host1:8095,host2:8095", cbanalytics.NewBasicAuthCredential("username", "password"), DefaultOptions())
+	require.NoError(t, err)
+
+	err = cluster.Close()
+	assert.NoError(t, err)
+}
+
+func TestMultipleHostsInvalidHost(t *testing.T) {
+	_, err := cbanalytics.NewCluster("http://host1:8095,:8095", cbanalytics.NewBasicAuthCredential("username", "password"), DefaultOptions())
+
+	assert.ErrorIs(t, err, cbanalytics.ErrInvalidArgument)
+}
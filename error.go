@@ -18,6 +18,8 @@ var ErrTimeout = errors.New("timeout error")
 
 // ErrQuery occurs when a server error is encountered while executing a query, excluding errors that caught by
 // ErrInvalidCredential or ErrTimeout.
+// Errors for well-known error codes, such as ErrParsingFailure and ErrDatasetNotFound, wrap ErrQuery so can be
+// matched by either.
 var ErrQuery = errors.New("query error")
 
 // ErrInvalidArgument occurs when an invalid argument is provided to a function.
@@ -36,9 +38,42 @@ var ErrServiceUnavailable = errors.New("service unavailable")
 // typically because they have been discarded or canceled.
 var ErrQueryNotFound = errors.New("query not found")
 
-type analyticsErrorDesc struct {
-	Code    uint32
+// ErrQueueFull occurs when a query cannot be sent because the maximum number of queries are already in flight,
+// and the queue of queries waiting to be sent is full. See ClusterOptions.ConcurrencyLimitOptions.
+var ErrQueueFull = errors.New("query queue is full")
+
+// ErrCircuitOpen occurs when a request is not sent because the circuit breaker of every endpoint is open, following
+// repeated failures. See ClusterOptions.CircuitBreakerOptions.
+// VOLATILE: This API is subject to change at any time.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// ErrNoRows occurs when a query which is expected to return a row, such as one run with QueryOne, returns none.
+var ErrNoRows = errors.New("no rows in result set")
+
+// ErrTooManyRows occurs when a query which is expected to return a single row, such as one run with QueryOne,
+// returns more than one.
+var ErrTooManyRows = errors.New("too many rows in result set")
+
+// ErrNotScalar occurs when a query run with QueryScalar returns an object with other than exactly one field, when
+// a single value was expected.
+var ErrNotScalar = errors.New("row is not a single value")
+
+// ErrorDesc describes a single error returned by the Analytics service.
+type ErrorDesc struct {
+	// Code is the error code returned by the server.
+	Code uint32
+
+	// Message is the error message returned by the server.
 	Message string
+
+	// Retriable indicates whether the server considers the error to be retriable.
+	Retriable bool
+}
+
+type analyticsErrorDesc struct {
+	Code      uint32
+	Message   string
+	Retriable bool
 }
 
 func (e analyticsErrorDesc) MarshalJSON() ([]byte, error) {
@@ -66,6 +101,8 @@ type AnalyticsError struct {
 	endpoint         string
 	httpResponseCode int
 	retries          uint32
+	clientContextID  string
+	requestID        string
 }
 
 func newAnalyticsError(cause error, statement, endpoint string, statusCode int, retries uint32) AnalyticsError {
@@ -81,6 +118,8 @@ func newAnalyticsError(cause error, statement, endpoint string, statusCode int,
 		message:          "",
 		httpResponseCode: statusCode,
 		retries:          retries,
+		clientContextID:  "",
+		requestID:        "",
 	}
 }
 
@@ -120,6 +159,126 @@ func (e AnalyticsError) Unwrap() error {
 	return e.cause
 }
 
+// Statement returns the statement of the query which failed, or an empty string if the error is not for a query.
+// VOLATILE: This API is subject to change at any time.
+func (e AnalyticsError) Statement() string {
+	return e.statement
+}
+
+// Endpoint returns the host:port of the endpoint that the request was last sent to.
+// VOLATILE: This API is subject to change at any time.
+func (e AnalyticsError) Endpoint() string {
+	return e.endpoint
+}
+
+// HTTPStatusCode returns the HTTP status code of the response, or 0 if no response was received.
+// VOLATILE: This API is subject to change at any time.
+func (e AnalyticsError) HTTPStatusCode() int {
+	return e.httpResponseCode
+}
+
+// Retries returns the number of times that the request was retried.
+// VOLATILE: This API is subject to change at any time.
+func (e AnalyticsError) Retries() uint32 {
+	return e.retries
+}
+
+// Errors returns the errors returned by the server, in the order that they were returned.
+// VOLATILE: This API is subject to change at any time.
+func (e AnalyticsError) Errors() []ErrorDesc {
+	if len(e.errors) == 0 {
+		return nil
+	}
+
+	descs := make([]ErrorDesc, len(e.errors))
+	for i, desc := range e.errors {
+		descs[i] = ErrorDesc{
+			Code:      desc.Code,
+			Message:   desc.Message,
+			Retriable: desc.Retriable,
+		}
+	}
+
+	return descs
+}
+
+// ClientContextID returns the client context ID that the query was sent with, or an empty string if the error
+// is not for a query.
+// VOLATILE: This API is subject to change at any time.
+func (e AnalyticsError) ClientContextID() string {
+	return e.clientContextID
+}
+
+// RequestID returns the request ID assigned to the query by the server, or an empty string if the server did not
+// return one.
+// VOLATILE: This API is subject to change at any time.
+func (e AnalyticsError) RequestID() string {
+	return e.requestID
+}
+
+// analyticsErrorJSON is the JSON representation of an AnalyticsError, using the same field names as Error.
+// Fields may be added to it, but existing fields are not renamed or removed.
+type analyticsErrorJSON struct {
+	Cause           string          `json:"cause"`
+	Message         string          `json:"message,omitempty"`
+	Statement       string          `json:"statement,omitempty"`
+	Endpoint        string          `json:"endpoint,omitempty"`
+	HTTPStatusCode  int             `json:"status_code,omitempty"`
+	Retries         uint32          `json:"retries"`
+	ClientContextID string          `json:"client_context_id,omitempty"`
+	RequestID       string          `json:"request_id,omitempty"`
+	Errors          []errorDescJSON `json:"errors,omitempty"`
+	Code            int             `json:"code,omitempty"`
+	ServerMessage   string          `json:"server_message,omitempty"`
+}
+
+type errorDescJSON struct {
+	Code      uint32 `json:"code"`
+	Message   string `json:"msg"`
+	Retriable bool   `json:"retriable"`
+}
+
+func (e AnalyticsError) toJSON() analyticsErrorJSON {
+	var descs []errorDescJSON
+
+	for _, desc := range e.errors {
+		descs = append(descs, errorDescJSON(desc))
+	}
+
+	return analyticsErrorJSON{
+		Cause:           e.Unwrap().Error(),
+		Message:         e.message,
+		Statement:       e.statement,
+		Endpoint:        e.endpoint,
+		HTTPStatusCode:  e.httpResponseCode,
+		Retries:         e.retries,
+		ClientContextID: e.clientContextID,
+		RequestID:       e.requestID,
+		Errors:          descs,
+		Code:            0,
+		ServerMessage:   "",
+	}
+}
+
+// MarshalJSON implements the json.Marshaler interface, so that the details of the error can be logged or reported
+// as structured fields.
+// VOLATILE: This API is subject to change at any time.
+func (e AnalyticsError) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(e.toJSON())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal analytics error: %s", err) // nolint: err113, errorlint
+	}
+
+	return b, nil
+}
+
+func (e AnalyticsError) withRequestIDs(clientContextID, requestID string) *AnalyticsError {
+	e.clientContextID = clientContextID
+	e.requestID = requestID
+
+	return &e
+}
+
 // QueryError occurs when an error is returned in the errors field of the response body of a response
 // from the query server.
 type QueryError struct {
@@ -148,6 +307,22 @@ func (e QueryError) Unwrap() error {
 	return e.cause
 }
 
+// MarshalJSON implements the json.Marshaler interface. The JSON is that of the underlying AnalyticsError, with
+// the code and message of the error from the server added.
+// VOLATILE: This API is subject to change at any time.
+func (e QueryError) MarshalJSON() ([]byte, error) {
+	errJSON := e.cause.toJSON()
+	errJSON.Code = e.code
+	errJSON.ServerMessage = e.message
+
+	b, err := json.Marshal(errJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query error: %s", err) // nolint: err113, errorlint
+	}
+
+	return b, nil
+}
+
 func (e QueryError) withErrors(errors []analyticsErrorDesc) *QueryError {
 	e.cause.errors = errors
 
@@ -169,6 +344,8 @@ func newQueryError(cause error, statement, endpoint string, statusCode int, code
 			message:          "",
 			httpResponseCode: statusCode,
 			retries:          retries,
+			clientContextID:  "",
+			requestID:        "",
 		},
 		code:    code,
 		message: message,
@@ -0,0 +1,158 @@
+package cbanalytics
+
+// Error codes returned by the Analytics service, as reported by QueryError.Code.
+// VOLATILE: This API is subject to change at any time.
+const (
+	// ErrorCodeUnauthorized occurs when the credentials provided are not valid.
+	ErrorCodeUnauthorized = 20000
+
+	// ErrorCodePermissionDenied occurs when the user does not have permission to perform the request.
+	ErrorCodePermissionDenied = 20001
+
+	// ErrorCodeRequestTimeout occurs when the request reached its server side timeout and was cancelled.
+	ErrorCodeRequestTimeout = 21002
+
+	// ErrorCodeRequestCanceled occurs when the request was cancelled before it completed.
+	ErrorCodeRequestCanceled = 21003
+
+	// ErrorCodeServiceUnavailable occurs when the Analytics service is temporarily unavailable.
+	ErrorCodeServiceUnavailable = 23000
+
+	// ErrorCodeRebalanceInProgress occurs when the request cannot be performed while a rebalance is in progress.
+	ErrorCodeRebalanceInProgress = 23003
+
+	// ErrorCodeJobQueueFull occurs when the server has too many requests queued to accept another.
+	ErrorCodeJobQueueFull = 23007
+
+	// ErrorCodeParsingFailure occurs when the statement could not be parsed, such as because of a syntax error.
+	ErrorCodeParsingFailure = 24000
+
+	// ErrorCodeLinkNotFound occurs when a link referenced by the statement does not exist.
+	ErrorCodeLinkNotFound = 24006
+
+	// ErrorCodeDatasetNotFound occurs when a collection, also known as a dataset, does not exist.
+	ErrorCodeDatasetNotFound = 24025
+
+	// ErrorCodeScopeNotFound occurs when a database or scope, also known as a dataverse, does not exist.
+	ErrorCodeScopeNotFound = 24034
+
+	// ErrorCodeScopeExists occurs when creating a database or scope which already exists.
+	ErrorCodeScopeExists = 24039
+
+	// ErrorCodeDatasetExists occurs when creating a collection which already exists.
+	ErrorCodeDatasetExists = 24040
+
+	// ErrorCodeDatasetNotFoundWithName occurs when a collection referenced by name does not exist.
+	ErrorCodeDatasetNotFoundWithName = 24044
+
+	// ErrorCodeDatasetNotFoundInScope occurs when a collection does not exist in the database and scope given.
+	ErrorCodeDatasetNotFoundInScope = 24045
+
+	// ErrorCodeIndexNotFound occurs when an index referenced by the statement does not exist.
+	ErrorCodeIndexNotFound = 24047
+
+	// ErrorCodeIndexExists occurs when creating an index which already exists.
+	ErrorCodeIndexExists = 24048
+
+	// ErrorCodeLinkExists occurs when creating a link which already exists.
+	ErrorCodeLinkExists = 24055
+
+	// ErrorCodeTypeMismatch occurs when a value does not have the type required by the statement.
+	ErrorCodeTypeMismatch = 24057
+
+	// ErrorCodeInternalServerFailure occurs when the server encountered an unexpected internal error.
+	ErrorCodeInternalServerFailure = 25000
+)
+
+// queryErrorCause is the type of the sentinel errors for specific query failures. Each of them wraps ErrQuery, so
+// that errors.Is(err, ErrQuery) continues to match every error returned by the server.
+type queryErrorCause struct {
+	message string
+}
+
+func (e *queryErrorCause) Error() string {
+	return e.message
+}
+
+func (e *queryErrorCause) Unwrap() error {
+	return ErrQuery
+}
+
+// ErrPermissionDenied occurs when the user does not have permission to perform the request.
+// VOLATILE: This API is subject to change at any time.
+var ErrPermissionDenied error = &queryErrorCause{message: "permission denied"}
+
+// ErrRequestCanceled occurs when the server cancelled the request before it completed.
+// VOLATILE: This API is subject to change at any time.
+var ErrRequestCanceled error = &queryErrorCause{message: "request canceled"}
+
+// ErrResourceExhausted occurs when the server does not have the resources to accept the request, such as
+// because its job queue is full.
+// VOLATILE: This API is subject to change at any time.
+var ErrResourceExhausted error = &queryErrorCause{message: "resource exhausted"}
+
+// ErrParsingFailure occurs when the statement could not be parsed, such as because of a syntax error.
+// VOLATILE: This API is subject to change at any time.
+var ErrParsingFailure error = &queryErrorCause{message: "parsing failure"}
+
+// ErrLinkNotFound occurs when a link referenced by the statement does not exist.
+// VOLATILE: This API is subject to change at any time.
+var ErrLinkNotFound error = &queryErrorCause{message: "link not found"}
+
+// ErrDatasetNotFound occurs when a collection referenced by the statement does not exist.
+// VOLATILE: This API is subject to change at any time.
+var ErrDatasetNotFound error = &queryErrorCause{message: "dataset not found"}
+
+// ErrScopeNotFound occurs when a database or scope referenced by the statement does not exist.
+// VOLATILE: This API is subject to change at any time.
+var ErrScopeNotFound error = &queryErrorCause{message: "scope not found"}
+
+// ErrIndexNotFound occurs when an index referenced by the statement does not exist.
+// VOLATILE: This API is subject to change at any time.
+var ErrIndexNotFound error = &queryErrorCause{message: "index not found"}
+
+// ErrAlreadyExists occurs when creating a database, scope, collection, index or link which already exists.
+// VOLATILE: This API is subject to change at any time.
+var ErrAlreadyExists error = &queryErrorCause{message: "already exists"}
+
+// ErrTypeMismatch occurs when a value does not have the type required by the statement.
+// VOLATILE: This API is subject to change at any time.
+var ErrTypeMismatch error = &queryErrorCause{message: "type mismatch"}
+
+// ErrInternalServerFailure occurs when the server encountered an unexpected internal error.
+// VOLATILE: This API is subject to change at any time.
+var ErrInternalServerFailure error = &queryErrorCause{message: "internal server failure"}
+
+// errorCodeCauses maps error codes returned by the server to the error which is the cause of the QueryError
+// returned for them. Codes which are not in the table map to ErrQuery.
+var errorCodeCauses = map[int]error{
+	ErrorCodeUnauthorized:            ErrInvalidCredential,
+	ErrorCodePermissionDenied:        ErrPermissionDenied,
+	ErrorCodeRequestTimeout:          ErrTimeout,
+	ErrorCodeRequestCanceled:         ErrRequestCanceled,
+	ErrorCodeServiceUnavailable:      ErrServiceUnavailable,
+	ErrorCodeRebalanceInProgress:     ErrServiceUnavailable,
+	ErrorCodeJobQueueFull:            ErrResourceExhausted,
+	ErrorCodeParsingFailure:          ErrParsingFailure,
+	ErrorCodeLinkNotFound:            ErrLinkNotFound,
+	ErrorCodeDatasetNotFound:         ErrDatasetNotFound,
+	ErrorCodeScopeNotFound:           ErrScopeNotFound,
+	ErrorCodeScopeExists:             ErrAlreadyExists,
+	ErrorCodeDatasetExists:           ErrAlreadyExists,
+	ErrorCodeDatasetNotFoundWithName: ErrDatasetNotFound,
+	ErrorCodeDatasetNotFoundInScope:  ErrDatasetNotFound,
+	ErrorCodeIndexNotFound:           ErrIndexNotFound,
+	ErrorCodeIndexExists:             ErrAlreadyExists,
+	ErrorCodeLinkExists:              ErrAlreadyExists,
+	ErrorCodeTypeMismatch:            ErrTypeMismatch,
+	ErrorCodeInternalServerFailure:   ErrInternalServerFailure,
+}
+
+// errorForCode returns the error which is the cause of the QueryError for an error code returned by the server.
+func errorForCode(code int) error {
+	if cause, ok := errorCodeCauses[code]; ok {
+		return cause
+	}
+
+	return ErrQuery
+}
@@ -1,10 +1,17 @@
 package cbanalytics
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/couchbase/gocbanalytics/analyticstest"
+	"github.com/couchbase/gocbanalytics/internal/httpqueryclient"
 )
 
 func TestQueryErrorAsAnalyticsError(t *testing.T) {
@@ -31,3 +38,128 @@ func TestQueryErrorAsQueryError(t *testing.T) {
 	assert.Equal(t, 23, queryError.Code())
 	assert.Equal(t, "message", queryError.Message())
 }
+
+func TestTranslateClientErrorCodes(t *testing.T) {
+	tests := []struct {
+		code     uint32
+		expected error
+	}{
+		{ErrorCodeUnauthorized, ErrInvalidCredential},
+		{ErrorCodePermissionDenied, ErrPermissionDenied},
+		{ErrorCodeRequestTimeout, ErrTimeout},
+		{ErrorCodeRebalanceInProgress, ErrServiceUnavailable},
+		{ErrorCodeJobQueueFull, ErrResourceExhausted},
+		{ErrorCodeParsingFailure, ErrParsingFailure},
+		{ErrorCodeDatasetNotFoundInScope, ErrDatasetNotFound},
+		{ErrorCodeScopeNotFound, ErrScopeNotFound},
+		{ErrorCodeIndexExists, ErrAlreadyExists},
+		{ErrorCodeTypeMismatch, ErrTypeMismatch},
+		{24999, ErrQuery},
+	}
+
+	for _, test := range tests {
+		err := translateClientError(&httpqueryclient.QueryError{ //nolint:exhaustruct
+			InnerError: errors.New("query failed"), //nolint:err113
+			Statement:  "SELECT 1",
+			Errors: []httpqueryclient.ErrorDesc{
+				{Code: test.code, Message: "message", Retry: false},
+			},
+		})
+
+		require.ErrorIs(t, err, test.expected, test.code)
+
+		var queryError *QueryError
+
+		require.ErrorAs(t, err, &queryError)
+		assert.Equal(t, int(test.code), queryError.Code())
+	}
+}
+
+func TestTranslateClientErrorContextCanceled(t *testing.T) {
+	translate := func(code uint32) error {
+		return translateClientError(&httpqueryclient.QueryError{ //nolint:exhaustruct
+			InnerError: context.Canceled,
+			Statement:  "SELECT 1",
+			Errors: []httpqueryclient.ErrorDesc{
+				{Code: code, Message: "message", Retry: true},
+			},
+		})
+	}
+
+	// The request being canceled is reported rather than the error from an earlier attempt.
+	for _, code := range []uint32{ErrorCodeServiceUnavailable, ErrorCodeRequestTimeout, ErrorCodeJobQueueFull} {
+		require.ErrorIs(t, translate(code), context.Canceled, code)
+	}
+
+	require.ErrorIs(t, translate(ErrorCodeUnauthorized), ErrInvalidCredential)
+	require.ErrorIs(t, translate(ErrorCodePermissionDenied), ErrPermissionDenied)
+}
+
+func TestSpecificQueryErrorsAreErrQuery(t *testing.T) {
+	for _, cause := range errorCodeCauses {
+		switch cause {
+		case ErrInvalidCredential, ErrTimeout, ErrServiceUnavailable:
+			require.NotErrorIs(t, cause, ErrQuery)
+		default:
+			require.ErrorIs(t, cause, ErrQuery, cause.Error())
+		}
+	}
+}
+
+func TestAnalyticsErrorAccessors(t *testing.T) {
+	srv := newFakeServer(t, analyticstest.Errors(
+		analyticstest.Error{Code: 23007, Message: "job queue full", Retriable: true},
+		analyticstest.Error{Code: 24045, Message: "cannot find dataset", Retriable: false},
+	))
+	client := newTestHTTPQueryClient(t, fakeServerAddr(t, srv), NewNoopMeter())
+
+	_, err := client.Query(context.Background(), "SELECT * FROM missing",
+		NewQueryOptions().SetClientContextID("ctx-1").SetMaxRetries(0))
+	require.ErrorIs(t, err, ErrDatasetNotFound)
+
+	var analyticsErr *AnalyticsError
+
+	require.ErrorAs(t, err, &analyticsErr)
+
+	assert.Equal(t, "SELECT * FROM missing", analyticsErr.Statement())
+	assert.Equal(t, fakeServerAddr(t, srv), analyticsErr.Endpoint())
+	assert.Equal(t, http.StatusOK, analyticsErr.HTTPStatusCode())
+	assert.Equal(t, uint32(0), analyticsErr.Retries())
+	assert.Equal(t, "ctx-1", analyticsErr.ClientContextID())
+	assert.Equal(t, "req-1", analyticsErr.RequestID())
+	assert.Equal(t, []ErrorDesc{
+		{Code: 23007, Message: "job queue full", Retriable: true},
+		{Code: 24045, Message: "cannot find dataset", Retriable: false},
+	}, analyticsErr.Errors())
+}
+
+func TestQueryErrorMarshalJSON(t *testing.T) {
+	err := newQueryError(ErrDatasetNotFound, "SELECT 1", "host:8095", 200, 24045, "cannot find dataset", 2).
+		withErrors([]analyticsErrorDesc{{Code: 24045, Message: "cannot find dataset", Retriable: false}})
+	err.cause = err.cause.withRequestIDs("ctx-1", "req-1")
+
+	b, marshalErr := json.Marshal(err)
+	require.NoError(t, marshalErr)
+
+	assert.JSONEq(t, `{
+		"cause": "dataset not found",
+		"statement": "SELECT 1",
+		"endpoint": "host:8095",
+		"status_code": 200,
+		"retries": 2,
+		"client_context_id": "ctx-1",
+		"request_id": "req-1",
+		"errors": [{"code": 24045, "msg": "cannot find dataset", "retriable": false}],
+		"code": 24045,
+		"server_message": "cannot find dataset"
+	}`, string(b))
+
+	// The field names match those used by Error.
+	assert.Contains(t, err.Error(), `"status_code":200`)
+	assert.Contains(t, err.Error(), `"msg":"cannot find dataset"`)
+
+	b, marshalErr = json.Marshal(newAnalyticsError(ErrTimeout, "", "host:8095", 0, 1).withMessage("timed out"))
+	require.NoError(t, marshalErr)
+
+	assert.JSONEq(t, `{"cause":"timeout error","message":"timed out","endpoint":"host:8095","retries":1}`, string(b))
+}
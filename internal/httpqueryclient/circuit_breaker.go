@@ -0,0 +1,177 @@
+package httpqueryclient
+
+import (
+	"sync"
+	"time"
+
+	"github.com/couchbase/gocbanalytics/internal/logging"
+)
+
+// CircuitBreakerConfig holds the configuration for the circuit breaker of each endpoint.
+type CircuitBreakerConfig struct {
+	// Enabled specifies whether circuit breakers are used.
+	Enabled bool
+
+	// FailureThreshold is the number of consecutive failed requests to an endpoint after which its circuit opens.
+	FailureThreshold int
+
+	// CoolDown is how long a circuit stays open before allowing a single request through to test the endpoint.
+	CoolDown time.Duration
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitClosed:
+		return "closed"
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// circuitBreaker stops requests from being sent to an endpoint which is failing.
+//
+// The circuit opens once FailureThreshold consecutive requests have failed, after which requests are refused until
+// CoolDown has passed. The circuit then half-opens, allowing a single request through. If that succeeds the circuit
+// closes, otherwise it opens again.
+type circuitBreaker struct {
+	endpoint  string
+	threshold int
+	coolDown  time.Duration
+	logger    logging.Logger
+
+	lock     sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+	// probing is set while the single request allowed through a half-open circuit is in flight.
+	probing bool
+}
+
+func newCircuitBreaker(config CircuitBreakerConfig, endpoint string, logger logging.Logger) *circuitBreaker {
+	if !config.Enabled {
+		return nil
+	}
+
+	threshold := config.FailureThreshold
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	return &circuitBreaker{
+		endpoint:  endpoint,
+		threshold: threshold,
+		coolDown:  config.CoolDown,
+		logger:    logger,
+		lock:      sync.Mutex{},
+		state:     circuitClosed,
+		failures:  0,
+		openedAt:  time.Time{},
+		probing:   false,
+	}
+}
+
+// allow returns whether a request may be sent to the endpoint. Every allowed request must be followed by a call
+// to recordSuccess, recordFailure or recordAbort. A nil circuit breaker allows every request.
+func (b *circuitBreaker) allow() bool {
+	if b == nil {
+		return true
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if time.Since(b.openedAt) < b.coolDown {
+			return false
+		}
+
+		b.transition(circuitHalfOpen)
+		b.probing = true
+
+		return true
+	case circuitHalfOpen:
+		if b.probing {
+			return false
+		}
+
+		b.probing = true
+
+		return true
+	default:
+		return true
+	}
+}
+
+// recordSuccess records that a request to the endpoint received a response.
+func (b *circuitBreaker) recordSuccess() {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.failures = 0
+	b.probing = false
+
+	if b.state != circuitClosed {
+		b.transition(circuitClosed)
+	}
+}
+
+// recordFailure records that a request to the endpoint failed because the endpoint is unreachable or unavailable.
+func (b *circuitBreaker) recordFailure() {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.failures++
+	b.probing = false
+
+	if b.state == circuitHalfOpen || (b.state == circuitClosed && b.failures >= b.threshold) {
+		b.openedAt = time.Now()
+		b.transition(circuitOpen)
+	}
+}
+
+// recordAbort records that an allowed request ended without telling us anything about the endpoint, such as
+// when its context was cancelled.
+func (b *circuitBreaker) recordAbort() {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	b.probing = false
+	b.lock.Unlock()
+}
+
+// transition changes the state of the circuit. The lock must be held.
+func (b *circuitBreaker) transition(state circuitState) {
+	if b.logger != nil {
+		if state == circuitOpen {
+			b.logger.Warn("Circuit breaker for endpoint %s changed from %s to %s after %d failures",
+				b.endpoint, b.state, state, b.failures)
+		} else {
+			b.logger.Info("Circuit breaker for endpoint %s changed from %s to %s", b.endpoint, b.state, state)
+		}
+	}
+
+	b.state = state
+}
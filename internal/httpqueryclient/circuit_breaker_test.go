@@ -0,0 +1,118 @@
+package httpqueryclient
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/couchbase/gocbanalytics/internal/logging"
+)
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	breaker := newCircuitBreaker(CircuitBreakerConfig{Enabled: false, FailureThreshold: 1, CoolDown: time.Second},
+		"localhost:8095", nil)
+	assert.Nil(t, breaker)
+
+	breaker.recordFailure()
+	assert.True(t, breaker.allow())
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	breaker := newCircuitBreaker(CircuitBreakerConfig{Enabled: true, FailureThreshold: 2, CoolDown: 20 * time.Millisecond},
+		"localhost:8095", logging.NewDefaultLogger(logging.LogTrace, 0))
+
+	require.True(t, breaker.allow())
+	breaker.recordFailure()
+	assert.Equal(t, circuitClosed, breaker.state)
+
+	// A success resets the count of consecutive failures.
+	require.True(t, breaker.allow())
+	breaker.recordSuccess()
+
+	for range 2 {
+		require.True(t, breaker.allow())
+		breaker.recordFailure()
+	}
+
+	assert.Equal(t, circuitOpen, breaker.state)
+	assert.False(t, breaker.allow())
+
+	time.Sleep(30 * time.Millisecond)
+
+	// Only a single probe is allowed through once half-open.
+	require.True(t, breaker.allow())
+	assert.Equal(t, circuitHalfOpen, breaker.state)
+	assert.False(t, breaker.allow())
+
+	// A failed probe opens the circuit again.
+	breaker.recordFailure()
+	assert.Equal(t, circuitOpen, breaker.state)
+	assert.False(t, breaker.allow())
+
+	time.Sleep(30 * time.Millisecond)
+
+	// An aborted probe tells us nothing, so another is allowed.
+	require.True(t, breaker.allow())
+	breaker.recordAbort()
+	require.True(t, breaker.allow())
+
+	breaker.recordSuccess()
+	assert.Equal(t, circuitClosed, breaker.state)
+	assert.True(t, breaker.allow())
+}
+
+func TestQueryCircuitOpenFailsFast(t *testing.T) {
+	var attempt int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&attempt, 1)
+		w.WriteHeader(503)
+		mustWrite(t, w, []byte(`{}`))
+	}))
+	defer srv.Close()
+
+	host, portStr, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+
+	port, err := strconv.Atoi(portStr)
+	require.NoError(t, err)
+
+	client := NewClient("http", []Endpoint{{Host: host, Port: port}}, ClientConfig{ //nolint:exhaustruct
+		Logger:         logging.NewDefaultLogger(logging.LogTrace, 0),
+		ConnectTimeout: 5 * time.Second,
+		CircuitBreaker: CircuitBreakerConfig{
+			Enabled:          true,
+			FailureThreshold: 2,
+			CoolDown:         time.Minute,
+		},
+	})
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err = client.Query(ctx, &QueryOptions{
+		Payload:     map[string]interface{}{"statement": "SELECT 1"},
+		AuthHandler: func(_ *http.Request) {},
+		MaxRetries:  5,
+	})
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempt))
+
+	// Later queries are refused without contacting the server.
+	_, err = client.Query(ctx, &QueryOptions{
+		Payload:     map[string]interface{}{"statement": "SELECT 1"},
+		AuthHandler: func(_ *http.Request) {},
+		MaxRetries:  5,
+	})
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempt))
+}
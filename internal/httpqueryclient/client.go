@@ -7,79 +7,214 @@ import (
 	"errors"
 	"net"
 	"net/http"
+	"net/url"
+	"sync"
 	"time"
 
 	"github.com/couchbase/gocbanalytics/internal/logging"
 )
 
+// TransportConfig holds the configuration for the HTTP transport created for each endpoint.
+// Zero values use the defaults of http.Transport, except where noted.
+type TransportConfig struct {
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout of 0 means that idle connections are never closed.
+	IdleConnTimeout time.Duration
+	MaxConnsPerHost int
+	DisableHTTP2    bool
+	ReadBufferSize  int
+	WriteBufferSize int
+	// TCPKeepAlive of 0 uses the default interval of net.Dialer, a negative value disables keep-alive probes.
+	TCPKeepAlive time.Duration
+	// Proxy returns the proxy to send a request through, or nil if it should be sent directly, as for
+	// http.Transport. It is called with the host of each endpoint to decide whether the endpoint is proxied.
+	// Hosts of proxied endpoints are resolved by the proxy, rather than the client.
+	Proxy func(*http.Request) (*url.URL, error)
+}
+
 // ClientConfig holds the configuration for the client.
 type ClientConfig struct {
 	TLSConfig      *tls.Config
 	Logger         logging.Logger
 	ConnectTimeout time.Duration
+	Transport      TransportConfig
+
+	// RetryStrategy is the default strategy used to decide whether to retry requests.
+	// If nil then exponential backoff with jitter is used.
+	RetryStrategy RetryStrategy
+
+	// Limiter limits the number of queries in flight at once.
+	Limiter LimiterConfig
+
+	// RetryBudget limits the number of retries made across all requests.
+	RetryBudget RetryBudgetConfig
+
+	// CircuitBreaker configures the circuit breaker of each endpoint.
+	CircuitBreaker CircuitBreakerConfig
+
+	// Redaction specifies how statements are redacted in errors and log messages.
+	Redaction RedactionLevel
+
+	// WrapTransport, if set, is called with the transport created for each endpoint and returns the transport
+	// that requests to that endpoint are sent through.
+	WrapTransport func(http.RoundTripper) http.RoundTripper
+
+	// SkipHostLookup specifies that hosts are not resolved before sending requests, such as when the transport
+	// replays recorded responses without contacting the server.
+	SkipHostLookup bool
 }
 
 // Client represents an HTTP client that can be used to make requests to the server.
 type Client struct {
-	scheme      string
-	host        string
-	port        int
-	innerClient *http.Client
-	resolver    *net.Resolver
-	logger      logging.Logger
+	scheme    string
+	endpoints *endpointManager
+	resolver  *net.Resolver
+	logger    logging.Logger
+
+	skipHostLookup bool
+
+	retryStrategy RetryStrategy
+	limiter       *concurrencyLimiter
+	retryBudget   *retryBudget
+	redaction     RedactionLevel
+
+	// cleanupWg tracks background requests, such as cancelling abandoned queries, which Close waits for.
+	// cleanupLock guards adding to cleanupWg, so that no request is added once closed is set.
+	cleanupWg   sync.WaitGroup
+	cleanupLock sync.Mutex
+	closed      bool
 }
 
-// NewClient creates a new Client with the given endpoint and configuration.
-func NewClient(scheme string, host string, port int, config ClientConfig) *Client {
-	client, resolver := createHTTPClient(config.TLSConfig, config.ConnectTimeout)
+// NewClient creates a new Client with the given endpoints and configuration.
+// Requests are spread across the endpoints, failing over to other endpoints when one becomes unavailable.
+func NewClient(scheme string, endpoints []Endpoint, config ClientConfig) *Client {
+	var resolver *net.Resolver
+
+	states := make([]*endpointState, len(endpoints))
+	for i, endpoint := range endpoints {
+		// Each endpoint gets its own transport so that the TLS server name matches the host being connected to.
+		var tlsConfig *tls.Config
+		if config.TLSConfig != nil {
+			tlsConfig = config.TLSConfig.Clone()
+			tlsConfig.ServerName = endpoint.Host
+		}
+
+		// Whether an endpoint is proxied is decided once from its host. Requests to other endpoints are addressed
+		// to resolved IPs, which NO_PROXY host names would not match, so their transports get no proxy at all.
+		proxied := endpointIsProxied(config.Transport.Proxy, scheme, endpoint)
+
+		transportConfig := config.Transport
+		if !proxied {
+			transportConfig.Proxy = nil
+		}
+
+		var client *http.Client
+
+		client, resolver = createHTTPClient(tlsConfig, config.ConnectTimeout, transportConfig)
+
+		if config.WrapTransport != nil {
+			client.Transport = config.WrapTransport(client.Transport)
+		}
+
+		states[i] = &endpointState{
+			Endpoint:         endpoint,
+			innerClient:      client,
+			breaker:          newCircuitBreaker(config.CircuitBreaker, endpoint.String(), config.Logger),
+			proxied:          proxied,
+			lock:             sync.Mutex{},
+			quarantinedUntil: time.Time{},
+		}
+	}
+
+	retryStrategy := config.RetryStrategy
+	if retryStrategy == nil {
+		retryStrategy = NewDefaultRetryStrategy()
+	}
 
 	return &Client{
-		scheme:      scheme,
-		host:        host,
-		port:        port,
-		innerClient: client,
-		resolver:    resolver,
-		logger:      config.Logger,
+		scheme: scheme,
+		endpoints: &endpointManager{
+			endpoints:          states,
+			quarantineDuration: defaultEndpointQuarantineDuration,
+		},
+		resolver:       resolver,
+		logger:         config.Logger,
+		skipHostLookup: config.SkipHostLookup,
+		retryStrategy:  retryStrategy,
+		limiter:        newConcurrencyLimiter(config.Limiter),
+		retryBudget:    newRetryBudget(config.RetryBudget),
+		redaction:      config.Redaction,
+		cleanupWg:      sync.WaitGroup{},
+		cleanupLock:    sync.Mutex{},
+		closed:         false,
 	}
 }
 
-// Host returns the host that the client is connected to.
+// Host returns the host of the first endpoint that the client is configured with.
 func (c *Client) Host() string {
-	return c.host
+	return c.endpoints.endpoints[0].Host
+}
+
+// Endpoints returns the endpoints that the client is configured with.
+func (c *Client) Endpoints() []Endpoint {
+	endpoints := make([]Endpoint, len(c.endpoints.endpoints))
+	for i, endpoint := range c.endpoints.endpoints {
+		endpoints[i] = endpoint.Endpoint
+	}
+
+	return endpoints
 }
 
 // Close closes the client and releases any resources it holds.
 func (c *Client) Close() error {
-	if tsport, ok := c.innerClient.Transport.(*http.Transport); ok {
-		tsport.CloseIdleConnections()
+	c.cleanupLock.Lock()
+	c.closed = true
+	c.cleanupLock.Unlock()
+
+	c.cleanupWg.Wait()
+
+	for _, endpoint := range c.endpoints.endpoints {
+		endpoint.innerClient.CloseIdleConnections()
 	}
 
 	return nil
 }
 
-func createHTTPClient(tlsConfig *tls.Config, connectTimeout time.Duration) (*http.Client, *net.Resolver) {
+func createHTTPClient(tlsConfig *tls.Config, connectTimeout time.Duration,
+	transportConfig TransportConfig) (*http.Client, *net.Resolver) {
 	resolver := net.DefaultResolver
 
 	httpDialer := &net.Dialer{ //nolint:exhaustruct
 		Timeout:   connectTimeout,
-		KeepAlive: 30 * time.Second,
+		KeepAlive: transportConfig.TCPKeepAlive,
 		Resolver:  resolver,
 	}
 
+	proxy, dialContext := withTLSProxySupport(transportConfig.Proxy,
+		func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return httpDialer.DialContext(ctx, network, addr)
+		})
+
 	// We set ForceAttemptHTTP2, which will update the base-config to support HTTP2
 	// automatically, so that all configs from it will look for that.
 	httpTransport := &http.Transport{ //nolint:exhaustruct
-		ForceAttemptHTTP2: true,
+		ForceAttemptHTTP2: !transportConfig.DisableHTTP2,
 
-		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-			return httpDialer.DialContext(ctx, network, addr)
-		},
+		DialContext: dialContext,
 
+		Proxy:               proxy,
 		TLSClientConfig:     tlsConfig,
 		MaxIdleConns:        0,
-		MaxIdleConnsPerHost: 0,
-		MaxConnsPerHost:     0,
-		IdleConnTimeout:     1000 * time.Millisecond,
+		MaxIdleConnsPerHost: transportConfig.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     transportConfig.MaxConnsPerHost,
+		IdleConnTimeout:     transportConfig.IdleConnTimeout,
+		ReadBufferSize:      transportConfig.ReadBufferSize,
+		WriteBufferSize:     transportConfig.WriteBufferSize,
+	}
+
+	if transportConfig.DisableHTTP2 {
+		// A non-nil, empty, map prevents the transport from negotiating HTTP/2 during the TLS handshake.
+		httpTransport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
 	}
 
 	httpCli := &http.Client{ //nolint:exhaustruct
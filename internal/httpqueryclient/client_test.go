@@ -0,0 +1,42 @@
+package httpqueryclient
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateHTTPClientTransportConfig(t *testing.T) {
+	client, _ := createHTTPClient(nil, time.Second, TransportConfig{
+		MaxIdleConnsPerHost: 16,
+		IdleConnTimeout:     time.Minute,
+		MaxConnsPerHost:     32,
+		DisableHTTP2:        true,
+		ReadBufferSize:      1024,
+		WriteBufferSize:     2048,
+		TCPKeepAlive:        time.Second,
+	})
+
+	transport, ok := client.Transport.(*http.Transport)
+	require.True(t, ok)
+
+	assert.Equal(t, 16, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, time.Minute, transport.IdleConnTimeout)
+	assert.Equal(t, 32, transport.MaxConnsPerHost)
+	assert.Equal(t, 1024, transport.ReadBufferSize)
+	assert.Equal(t, 2048, transport.WriteBufferSize)
+	assert.False(t, transport.ForceAttemptHTTP2)
+	assert.NotNil(t, transport.TLSNextProto)
+	assert.Empty(t, transport.TLSNextProto)
+
+	client, _ = createHTTPClient(nil, time.Second, TransportConfig{}) //nolint:exhaustruct
+
+	transport, ok = client.Transport.(*http.Transport)
+	require.True(t, ok)
+
+	assert.True(t, transport.ForceAttemptHTTP2)
+	assert.Nil(t, transport.TLSNextProto)
+}
@@ -0,0 +1,121 @@
+package httpqueryclient
+
+import (
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultEndpointQuarantineDuration is how long an endpoint is avoided for after it fails.
+const defaultEndpointQuarantineDuration = 5 * time.Second
+
+// Endpoint represents a single host and port that requests can be sent to.
+type Endpoint struct {
+	Host string
+	Port int
+}
+
+// String returns the host:port representation of the endpoint.
+func (e Endpoint) String() string {
+	return net.JoinHostPort(e.Host, strconv.Itoa(e.Port))
+}
+
+// endpointState tracks the health of an individual endpoint.
+type endpointState struct {
+	Endpoint
+
+	innerClient *http.Client
+	// breaker is nil when circuit breakers are disabled.
+	breaker *circuitBreaker
+	// proxied is set when requests to the endpoint are sent through a proxy, which resolves the host itself.
+	proxied bool
+
+	lock             sync.Mutex
+	quarantinedUntil time.Time
+}
+
+func (e *endpointState) isQuarantined(now time.Time) bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return now.Before(e.quarantinedUntil)
+}
+
+func (e *endpointState) quarantineExpiry() time.Time {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return e.quarantinedUntil
+}
+
+func (e *endpointState) quarantine(until time.Time) {
+	e.lock.Lock()
+	e.quarantinedUntil = until
+	e.lock.Unlock()
+}
+
+func (e *endpointState) markHealthy() {
+	e.lock.Lock()
+	e.quarantinedUntil = time.Time{}
+	e.lock.Unlock()
+}
+
+// endpointManager selects endpoints for requests, avoiding those which have recently failed.
+type endpointManager struct {
+	endpoints          []*endpointState
+	quarantineDuration time.Duration
+}
+
+// Select picks an endpoint to send a request to. Endpoints which are not quarantined are preferred,
+// and of those any endpoint other than previous is preferred, so that retries fail over to a different
+// endpoint where possible. If every endpoint is quarantined then the one which will leave quarantine
+// soonest is chosen.
+func (m *endpointManager) Select(previous *endpointState) *endpointState {
+	if len(m.endpoints) == 1 {
+		return m.endpoints[0]
+	}
+
+	now := time.Now()
+
+	var healthy []*endpointState
+
+	var soonest *endpointState
+
+	for _, endpoint := range m.endpoints {
+		if !endpoint.isQuarantined(now) {
+			healthy = append(healthy, endpoint)
+
+			continue
+		}
+
+		if soonest == nil || endpoint.quarantineExpiry().Before(soonest.quarantineExpiry()) {
+			soonest = endpoint
+		}
+	}
+
+	if len(healthy) == 0 {
+		return soonest
+	}
+
+	if previous != nil && len(healthy) > 1 {
+		candidates := make([]*endpointState, 0, len(healthy)-1)
+
+		for _, endpoint := range healthy {
+			if endpoint != previous {
+				candidates = append(candidates, endpoint)
+			}
+		}
+
+		healthy = candidates
+	}
+
+	return healthy[rand.Intn(len(healthy))] //nolint:gosec
+}
+
+// Quarantine marks the endpoint as unhealthy, so that it is avoided for subsequent requests.
+func (m *endpointManager) Quarantine(endpoint *endpointState) {
+	endpoint.quarantine(time.Now().Add(m.quarantineDuration))
+}
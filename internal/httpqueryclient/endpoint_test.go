@@ -0,0 +1,225 @@
+package httpqueryclient
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/couchbase/gocbanalytics/internal/logging"
+)
+
+func newTestEndpoint(t *testing.T, addr string) Endpoint {
+	t.Helper()
+
+	host, portStr, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+
+	port, err := strconv.Atoi(portStr)
+	require.NoError(t, err)
+
+	return Endpoint{Host: host, Port: port}
+}
+
+func newMultiEndpointTestClient(endpoints ...Endpoint) *Client {
+	return NewClient("http", endpoints, ClientConfig{
+		TLSConfig:      nil,
+		Logger:         logging.NewDefaultLogger(logging.LogTrace, 0),
+		ConnectTimeout: 5 * time.Second,
+		RetryStrategy:  NewExponentialBackoffRetryStrategy(time.Millisecond, 10*time.Millisecond, 2),
+	})
+}
+
+// refusingAddress returns an address on which nothing is listening.
+func refusingAddress(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	return addr
+}
+
+func TestEndpointManager_SelectPrefersHealthyEndpoints(t *testing.T) {
+	client := newMultiEndpointTestClient(Endpoint{Host: "a", Port: 1}, Endpoint{Host: "b", Port: 2})
+	defer client.Close()
+
+	mgr := client.endpoints
+	mgr.Quarantine(mgr.endpoints[0])
+
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, mgr.endpoints[1], mgr.Select(nil))
+	}
+}
+
+func TestEndpointManager_SelectFailsOverFromPrevious(t *testing.T) {
+	client := newMultiEndpointTestClient(Endpoint{Host: "a", Port: 1}, Endpoint{Host: "b", Port: 2})
+	defer client.Close()
+
+	mgr := client.endpoints
+
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, mgr.endpoints[1], mgr.Select(mgr.endpoints[0]))
+		assert.Equal(t, mgr.endpoints[0], mgr.Select(mgr.endpoints[1]))
+	}
+}
+
+func TestEndpointManager_SelectAllQuarantinedPicksSoonest(t *testing.T) {
+	client := newMultiEndpointTestClient(Endpoint{Host: "a", Port: 1}, Endpoint{Host: "b", Port: 2})
+	defer client.Close()
+
+	mgr := client.endpoints
+	mgr.endpoints[0].quarantine(time.Now().Add(time.Minute))
+	mgr.endpoints[1].quarantine(time.Now().Add(time.Second))
+
+	assert.Equal(t, mgr.endpoints[1], mgr.Select(nil))
+}
+
+func TestQueryRetries_FailsOverToHealthyEndpoint(t *testing.T) {
+	var attempts int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(200)
+		mustWrite(t, w, analyticsResponse(withResults(1)))
+	}))
+	defer srv.Close()
+
+	down := newTestEndpoint(t, refusingAddress(t))
+	up := newTestEndpoint(t, srv.Listener.Addr().String())
+
+	client := newMultiEndpointTestClient(down, up)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for i := 0; i < 5; i++ {
+		result, err := client.Query(ctx, &QueryOptions{
+			Payload:       map[string]interface{}{"statement": "SELECT 1"},
+			AuthHandler:   func(_ *http.Request) {},
+			MaxRetries:    1,
+			RetryStrategy: nil,
+		})
+		require.NoError(t, err)
+		require.NotNil(t, result.NextRow())
+		require.NoError(t, result.Close())
+	}
+
+	assert.Equal(t, int32(5), atomic.LoadInt32(&attempts))
+	assert.True(t, client.endpoints.endpoints[0].isQuarantined(time.Now()))
+	assert.False(t, client.endpoints.endpoints[1].isQuarantined(time.Now()))
+}
+
+func TestQueryRetries_ServiceUnavailableQuarantinesEndpoint(t *testing.T) {
+	var unavailableAttempts, healthyAttempts int32
+
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&unavailableAttempts, 1)
+		w.WriteHeader(503)
+		mustWrite(t, w, []byte(`{}`))
+	}))
+	defer unavailable.Close()
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&healthyAttempts, 1)
+		w.WriteHeader(503)
+		mustWrite(t, w, []byte(`{}`))
+	}))
+	defer healthy.Close()
+
+	client := newMultiEndpointTestClient(
+		newTestEndpoint(t, unavailable.Listener.Addr().String()),
+		newTestEndpoint(t, healthy.Listener.Addr().String()),
+	)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := client.Query(ctx, &QueryOptions{
+		Payload:       map[string]interface{}{"statement": "SELECT 1"},
+		AuthHandler:   func(_ *http.Request) {},
+		MaxRetries:    1,
+		RetryStrategy: nil,
+	})
+	require.ErrorIs(t, err, ErrServiceUnavailable)
+
+	// The retry should have failed over to the other endpoint.
+	assert.Equal(t, int32(1), atomic.LoadInt32(&unavailableAttempts))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&healthyAttempts))
+}
+
+func TestQueryError_EndpointReflectsEndpointUsed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(200)
+		mustWrite(t, w, analyticsResponse(
+			withStatus("fatal"),
+			withErrors(nonRetriableError(24000, "syntax error")),
+		))
+	}))
+	defer srv.Close()
+
+	down := newTestEndpoint(t, refusingAddress(t))
+	up := newTestEndpoint(t, srv.Listener.Addr().String())
+
+	client := newMultiEndpointTestClient(down, up)
+	defer client.Close()
+
+	// Make sure the first attempt goes to the down endpoint, so that the error must come from a failover.
+	client.endpoints.Quarantine(client.endpoints.endpoints[1])
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := client.Query(ctx, &QueryOptions{
+		Payload:       map[string]interface{}{"statement": "SELEC 1"},
+		AuthHandler:   func(_ *http.Request) {},
+		MaxRetries:    3,
+		RetryStrategy: nil,
+	})
+
+	var qErr *QueryError
+
+	require.ErrorAs(t, err, &qErr)
+	assert.Equal(t, up.String(), qErr.Endpoint)
+	assert.Equal(t, uint32(1), qErr.Retries)
+}
+
+func TestQueryRetries_LookupFailureFailsOver(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(200)
+		mustWrite(t, w, analyticsResponse(withResults(1)))
+	}))
+	defer srv.Close()
+
+	up := newTestEndpoint(t, srv.Listener.Addr().String())
+
+	client := newMultiEndpointTestClient(Endpoint{Host: "imnotarealboy.invalid", Port: up.Port}, up)
+	defer client.Close()
+
+	client.endpoints.Quarantine(client.endpoints.endpoints[1])
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := client.Query(ctx, &QueryOptions{
+		Payload:       map[string]interface{}{"statement": "SELECT 1"},
+		AuthHandler:   func(_ *http.Request) {},
+		MaxRetries:    0,
+		RetryStrategy: nil,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, result.NextRow())
+	require.NoError(t, result.Close())
+}
@@ -27,6 +27,13 @@ var (
 
 	// ErrServiceUnavailable occurs when the Analytics service, or a part of the system in the path to it, is unavailable.
 	ErrServiceUnavailable = errors.New("service unavailable")
+
+	// ErrQueueFull occurs when a query cannot be sent because the maximum number of queries are in flight, and
+	// the queue of queries waiting to be sent is full.
+	ErrQueueFull = errors.New("query queue is full")
+
+	// ErrCircuitOpen occurs when a request is not sent because the circuit breakers of all endpoints are open.
+	ErrCircuitOpen = errors.New("circuit breaker is open")
 )
 
 // ErrorDesc represents specific Analytics error data.
@@ -63,6 +70,8 @@ type QueryError struct {
 	ErrorText        string
 	HTTPResponseCode int
 	Retries          uint32
+	ClientContextID  string
+	RequestID        string
 }
 
 func newAnalyticsError(innerError error, statement string, endpoint string, responseCode int, retries uint32) *QueryError {
@@ -76,6 +85,8 @@ func newAnalyticsError(innerError error, statement string, endpoint string, resp
 		ErrorText:        "",
 		HTTPResponseCode: responseCode,
 		Retries:          retries,
+		ClientContextID:  "",
+		RequestID:        "",
 	}
 }
 
@@ -92,6 +103,12 @@ func (e QueryError) withLastDetail(code uint32, msg string) *QueryError {
 	return &e
 }
 
+func (e QueryError) withRequestID(requestID string) *QueryError {
+	e.RequestID = requestID
+
+	return &e
+}
+
 func (e QueryError) withErrors(errors []ErrorDesc) *QueryError {
 	e.Errors = errors
 
@@ -9,5 +9,6 @@ type jsonAnalyticsError struct {
 }
 
 type jsonAnalyticsErrorResponse struct {
-	Errors json.RawMessage `json:"errors"`
+	RequestID string          `json:"requestID"`
+	Errors    json.RawMessage `json:"errors"`
 }
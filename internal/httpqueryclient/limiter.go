@@ -0,0 +1,110 @@
+package httpqueryclient
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LimiterConfig holds the configuration for limiting the number of queries in flight at once.
+type LimiterConfig struct {
+	// MaxInFlight is the maximum number of queries in flight at once, or 0 for no limit.
+	MaxInFlight int
+
+	// MaxQueued is the maximum number of queries waiting for another to finish, or a negative value for no limit.
+	MaxQueued int
+}
+
+// concurrencyLimiter limits the number of queries in flight at once. Queries which cannot be sent immediately
+// wait in a FIFO queue.
+type concurrencyLimiter struct {
+	maxInFlight int
+	maxQueued   int
+
+	lock     sync.Mutex
+	inFlight int
+	// waiters holds a channel for each queued query, which is closed when it is granted a slot.
+	waiters list.List
+}
+
+func newConcurrencyLimiter(config LimiterConfig) *concurrencyLimiter {
+	if config.MaxInFlight <= 0 {
+		return nil
+	}
+
+	return &concurrencyLimiter{
+		maxInFlight: config.MaxInFlight,
+		maxQueued:   config.MaxQueued,
+		lock:        sync.Mutex{},
+		inFlight:    0,
+		waiters:     list.List{},
+	}
+}
+
+// acquire waits for a slot to send a query in, returning how long was spent waiting. Once the query has finished
+// release must be called. A nil limiter never waits.
+func (l *concurrencyLimiter) acquire(ctx context.Context) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+
+	l.lock.Lock()
+
+	// Queued queries are granted slots first, so that the queue is fair.
+	if l.inFlight < l.maxInFlight && l.waiters.Len() == 0 {
+		l.inFlight++
+		l.lock.Unlock()
+
+		return 0, nil
+	}
+
+	if l.maxQueued >= 0 && l.waiters.Len() >= l.maxQueued {
+		l.lock.Unlock()
+
+		return 0, ErrQueueFull
+	}
+
+	ready := make(chan struct{})
+	elem := l.waiters.PushBack(ready)
+	l.lock.Unlock()
+
+	start := time.Now()
+
+	select {
+	case <-ready:
+		return time.Since(start), nil
+	case <-ctx.Done():
+		l.lock.Lock()
+		select {
+		case <-ready:
+			// The slot was granted at the same time as the context finished, so hand it on.
+			l.lock.Unlock()
+			l.release()
+		default:
+			l.waiters.Remove(elem)
+			l.lock.Unlock()
+		}
+
+		return time.Since(start), ctx.Err()
+	}
+}
+
+// release frees the slot of a query which has finished, handing it to the first queued query if there is one.
+func (l *concurrencyLimiter) release() {
+	if l == nil {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if front := l.waiters.Front(); front != nil {
+		l.waiters.Remove(front)
+		close(front.Value.(chan struct{})) //nolint:forcetypeassert
+
+		return
+	}
+
+	l.inFlight--
+}
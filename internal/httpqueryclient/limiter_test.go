@@ -0,0 +1,107 @@
+package httpqueryclient
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyLimiterDisabled(t *testing.T) {
+	limiter := newConcurrencyLimiter(LimiterConfig{MaxInFlight: 0, MaxQueued: 0})
+	assert.Nil(t, limiter)
+
+	queueTime, err := limiter.acquire(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, queueTime)
+
+	limiter.release()
+}
+
+func TestConcurrencyLimiterQueueFull(t *testing.T) {
+	limiter := newConcurrencyLimiter(LimiterConfig{MaxInFlight: 1, MaxQueued: 0})
+
+	_, err := limiter.acquire(context.Background())
+	require.NoError(t, err)
+
+	_, err = limiter.acquire(context.Background())
+	require.ErrorIs(t, err, ErrQueueFull)
+
+	limiter.release()
+
+	_, err = limiter.acquire(context.Background())
+	require.NoError(t, err)
+}
+
+func TestConcurrencyLimiterFIFO(t *testing.T) {
+	limiter := newConcurrencyLimiter(LimiterConfig{MaxInFlight: 1, MaxQueued: -1})
+
+	_, err := limiter.acquire(context.Background())
+	require.NoError(t, err)
+
+	var lock sync.Mutex
+
+	var order []int
+
+	var wg sync.WaitGroup
+
+	for i := range 5 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			queueTime, err := limiter.acquire(context.Background())
+			assert.NoError(t, err)
+			assert.Positive(t, queueTime)
+
+			lock.Lock()
+			order = append(order, i)
+			lock.Unlock()
+
+			limiter.release()
+		}()
+
+		// Wait for each query to be queued before queueing the next.
+		require.Eventually(t, func() bool {
+			limiter.lock.Lock()
+			defer limiter.lock.Unlock()
+
+			return limiter.waiters.Len() == i+1
+		}, time.Second, time.Millisecond)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	limiter.release()
+	wg.Wait()
+
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, order)
+	assert.Equal(t, 0, limiter.inFlight)
+}
+
+func TestConcurrencyLimiterContextDone(t *testing.T) {
+	limiter := newConcurrencyLimiter(LimiterConfig{MaxInFlight: 1, MaxQueued: 1})
+
+	_, err := limiter.acquire(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	queueTime, err := limiter.acquire(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.GreaterOrEqual(t, queueTime, 20*time.Millisecond)
+
+	// The abandoned query no longer occupies the queue.
+	limiter.lock.Lock()
+	assert.Equal(t, 0, limiter.waiters.Len())
+	limiter.lock.Unlock()
+
+	limiter.release()
+
+	_, err = limiter.acquire(context.Background())
+	require.NoError(t, err)
+}
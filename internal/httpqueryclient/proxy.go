@@ -0,0 +1,231 @@
+package httpqueryclient
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// NewProxyFunc returns a function, suitable for TransportConfig.Proxy, which sends every request through
+// proxyURL unless the request host matches noProxy.
+//
+// noProxy follows the semantics of the NO_PROXY environment variable: it is a comma separated list of entries,
+// each of which is an IP address, a CIDR range, a domain name or "*". A domain name matches that domain and all
+// of its subdomains, and a domain name with a leading "." matches only subdomains. An entry may include a port,
+// in which case it only matches requests to that port.
+func NewProxyFunc(proxyURL *url.URL, noProxy string) func(*http.Request) (*url.URL, error) {
+	matchers := parseNoProxy(noProxy)
+
+	return func(req *http.Request) (*url.URL, error) {
+		host, port := splitHostPort(req.URL)
+
+		for _, matcher := range matchers {
+			if matcher.matches(host, port) {
+				return nil, nil //nolint:nilnil
+			}
+		}
+
+		return proxyURL, nil
+	}
+}
+
+type noProxyMatcher struct {
+	all    bool
+	ipNet  *net.IPNet
+	ip     net.IP
+	domain string
+	// subdomainsOnly is set for domains written with a leading ".".
+	subdomainsOnly bool
+	port           string
+}
+
+func (m noProxyMatcher) matches(host, port string) bool {
+	if m.all {
+		return true
+	}
+
+	if m.port != "" && m.port != port {
+		return false
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if m.ipNet != nil {
+			return m.ipNet.Contains(ip)
+		}
+
+		if m.ip != nil {
+			return m.ip.Equal(ip)
+		}
+	}
+
+	if m.domain == "" {
+		return false
+	}
+
+	if strings.HasSuffix(host, "."+m.domain) {
+		return true
+	}
+
+	return !m.subdomainsOnly && host == m.domain
+}
+
+func parseNoProxy(noProxy string) []noProxyMatcher {
+	var matchers []noProxyMatcher
+
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+
+		matcher := noProxyMatcher{
+			all:            false,
+			ipNet:          nil,
+			ip:             nil,
+			domain:         "",
+			subdomainsOnly: false,
+			port:           "",
+		}
+
+		if entry == "*" {
+			matcher.all = true
+			matchers = append(matchers, matcher)
+
+			continue
+		}
+
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			matcher.ipNet = ipNet
+			matchers = append(matchers, matcher)
+
+			continue
+		}
+
+		host := entry
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			host = h
+			matcher.port = p
+		}
+
+		if ip := net.ParseIP(host); ip != nil {
+			matcher.ip = ip
+			matchers = append(matchers, matcher)
+
+			continue
+		}
+
+		if strings.HasPrefix(host, "*.") {
+			host = host[1:]
+		}
+
+		if strings.HasPrefix(host, ".") {
+			matcher.subdomainsOnly = true
+			host = host[1:]
+		}
+
+		matcher.domain = host
+		matchers = append(matchers, matcher)
+	}
+
+	return matchers
+}
+
+func splitHostPort(u *url.URL) (string, string) {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	return strings.ToLower(u.Hostname()), port
+}
+
+// endpointIsProxied returns whether requests to the endpoint are sent through a proxy.
+func endpointIsProxied(proxy func(*http.Request) (*url.URL, error), scheme string, endpoint Endpoint) bool {
+	if proxy == nil {
+		return false
+	}
+
+	req, err := http.NewRequest(http.MethodGet, scheme+"://"+net.JoinHostPort(endpoint.Host,
+		strconv.Itoa(endpoint.Port)), nil)
+	if err != nil {
+		return false
+	}
+
+	proxyURL, err := proxy(req)
+
+	return err == nil && proxyURL != nil
+}
+
+type dialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// withTLSProxySupport adapts proxy and dial so that https proxies are connected to using a TLS configuration of
+// their own. http.Transport otherwise uses TLSClientConfig for connecting to https proxies, which would verify the
+// proxy against the server name and trusted certificates of the cluster.
+//
+// https proxies are presented to the transport as http proxies, and the connection to the proxy is upgraded to
+// TLS when it is dialed.
+func withTLSProxySupport(proxy func(*http.Request) (*url.URL, error), dial dialContextFunc) (
+	func(*http.Request) (*url.URL, error), dialContextFunc) {
+	if proxy == nil {
+		return nil, dial
+	}
+
+	var tlsProxies sync.Map
+
+	wrappedProxy := func(req *http.Request) (*url.URL, error) {
+		proxyURL, err := proxy(req)
+		if err != nil || proxyURL == nil || proxyURL.Scheme != "https" {
+			return proxyURL, err
+		}
+
+		port := proxyURL.Port()
+		if port == "" {
+			port = "443"
+		}
+
+		httpURL := *proxyURL
+		httpURL.Scheme = "http"
+		httpURL.Host = net.JoinHostPort(proxyURL.Hostname(), port)
+
+		tlsProxies.Store(httpURL.Host, proxyURL.Hostname())
+
+		return &httpURL, nil
+	}
+
+	wrappedDial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		serverName, ok := tlsProxies.Load(addr)
+		if !ok {
+			return conn, nil
+		}
+
+		tlsConn := tls.Client(conn, &tls.Config{ //nolint:exhaustruct
+			MinVersion: tls.VersionTLS12,
+			ServerName: serverName.(string), //nolint:forcetypeassert
+		})
+
+		err = tlsConn.HandshakeContext(ctx)
+		if err != nil {
+			_ = conn.Close()
+
+			return nil, fmt.Errorf("failed tls handshake with proxy %s: %w", addr, err)
+		}
+
+		return tlsConn, nil
+	}
+
+	return wrappedProxy, wrappedDial
+}
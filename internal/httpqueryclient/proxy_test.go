@@ -0,0 +1,315 @@
+package httpqueryclient
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/couchbase/gocbanalytics/internal/logging"
+)
+
+func TestNewProxyFuncNoProxy(t *testing.T) {
+	proxyURL, err := url.Parse("http://proxy:3128")
+	require.NoError(t, err)
+
+	tests := []struct {
+		noProxy string
+		target  string
+		proxied bool
+	}{
+		{"", "https://cluster.example.com", true},
+		{"*", "https://cluster.example.com", false},
+		{"example.com", "https://cluster.example.com", false},
+		{"example.com", "https://example.com", false},
+		{"example.com", "https://notexample.com", true},
+		{".example.com", "https://example.com", true},
+		{".example.com", "https://cluster.example.com", false},
+		{"*.example.com", "https://cluster.example.com", false},
+		{"other.com, EXAMPLE.com", "https://Cluster.Example.com", false},
+		{"example.com:8095", "https://cluster.example.com:8095", false},
+		{"example.com:8095", "https://cluster.example.com:18095", true},
+		{"example.com:443", "https://cluster.example.com", false},
+		{"10.0.0.0/8", "http://10.1.2.3:8095", false},
+		{"10.0.0.0/8", "http://192.168.0.1:8095", true},
+		{"192.168.0.1", "http://192.168.0.1:8095", false},
+		{"[::1]:8095", "http://[::1]:8095", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.noProxy+" "+test.target, func(tt *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, test.target, nil)
+			require.NoError(tt, err)
+
+			actual, err := NewProxyFunc(proxyURL, test.noProxy)(req)
+			require.NoError(tt, err)
+
+			if test.proxied {
+				assert.Equal(tt, proxyURL, actual)
+			} else {
+				assert.Nil(tt, actual)
+			}
+		})
+	}
+}
+
+func TestQueryThroughHTTPProxy(t *testing.T) {
+	var proxyAuth, requestURI string
+
+	// The proxy answers requests itself, as plain http requests are forwarded with the absolute URI.
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxyAuth = r.Header.Get("Proxy-Authorization")
+		requestURI = r.RequestURI
+
+		w.WriteHeader(http.StatusOK)
+		mustWrite(t, w, analyticsResponse(withResults(1)))
+	}))
+	t.Cleanup(proxy.Close)
+
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+
+	proxyURL.User = url.UserPassword("user", "pass")
+
+	// The cluster host cannot be resolved, so is left for the proxy to resolve.
+	client := NewClient("http", []Endpoint{{Host: "cluster.invalid", Port: 8095}}, ClientConfig{
+		TLSConfig:      nil,
+		Logger:         logging.NewDefaultLogger(logging.LogTrace, 0),
+		ConnectTimeout: 5 * time.Second,
+		Transport: TransportConfig{ //nolint:exhaustruct
+			Proxy: NewProxyFunc(proxyURL, "localhost"),
+		},
+		RetryStrategy: nil,
+		WrapTransport: nil,
+	})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	result, err := client.Query(context.Background(), &QueryOptions{
+		Payload:     map[string]interface{}{"statement": "SELECT 1"},
+		AuthHandler: func(_ *http.Request) {},
+		MaxRetries:  0,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "1", string(result.NextRow()))
+	assert.Nil(t, result.NextRow())
+	require.NoError(t, result.Err())
+
+	assert.Equal(t, "http://cluster.invalid:8095/api/v1/request", requestURI)
+	assert.Equal(t, "Basic "+base64.StdEncoding.EncodeToString([]byte("user:pass")), proxyAuth)
+}
+
+func TestQueryNoProxyBypassesProxy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		mustWrite(t, w, analyticsResponse(withResults(2)))
+	}))
+	t.Cleanup(srv.Close)
+
+	proxyURL, err := url.Parse("http://127.0.0.1:1")
+	require.NoError(t, err)
+
+	srvURL, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	port, err := strconv.Atoi(srvURL.Port())
+	require.NoError(t, err)
+
+	endpoint := Endpoint{Host: srvURL.Hostname(), Port: port}
+
+	config := TransportConfig{ //nolint:exhaustruct
+		Proxy: NewProxyFunc(proxyURL, "127.0.0.0/8"),
+	}
+
+	assert.False(t, endpointIsProxied(config.Proxy, "http", endpoint))
+
+	client := NewClient("http", []Endpoint{endpoint}, ClientConfig{
+		TLSConfig:      nil,
+		Logger:         logging.NewDefaultLogger(logging.LogTrace, 0),
+		ConnectTimeout: 5 * time.Second,
+		Transport:      config,
+		RetryStrategy:  nil,
+		WrapTransport:  nil,
+	})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	result, err := client.Query(context.Background(), &QueryOptions{
+		Payload:     map[string]interface{}{"statement": "SELECT 2"},
+		AuthHandler: func(_ *http.Request) {},
+		MaxRetries:  0,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "2", string(result.NextRow()))
+	assert.Nil(t, result.NextRow())
+	require.NoError(t, result.Err())
+}
+
+func TestQueryNoProxyHostBypassesProxy(t *testing.T) {
+	var proxied atomic.Int32
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		proxied.Add(1)
+
+		w.WriteHeader(http.StatusOK)
+		mustWrite(t, w, analyticsResponse(withResults(1)))
+	}))
+	t.Cleanup(proxy.Close)
+
+	var host string
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+
+		w.WriteHeader(http.StatusOK)
+		mustWrite(t, w, analyticsResponse(withResults(2)))
+	}))
+
+	// Listen on every address, as localhost may resolve to either the IPv4 or the IPv6 loopback address.
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+
+	srv.Listener = listener
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	endpoint := Endpoint{Host: "localhost", Port: port}
+
+	config := TransportConfig{ //nolint:exhaustruct
+		Proxy: NewProxyFunc(proxyURL, "localhost"),
+	}
+
+	assert.False(t, endpointIsProxied(config.Proxy, "http", endpoint))
+
+	client := NewClient("http", []Endpoint{endpoint}, ClientConfig{
+		TLSConfig:      nil,
+		Logger:         logging.NewDefaultLogger(logging.LogTrace, 0),
+		ConnectTimeout: 5 * time.Second,
+		Transport:      config,
+		RetryStrategy:  nil,
+		WrapTransport:  nil,
+	})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	// The request is addressed to the resolved IP of localhost, which the NO_PROXY entry does not match.
+	result, err := client.Query(context.Background(), &QueryOptions{
+		Payload:     map[string]interface{}{"statement": "SELECT 2"},
+		AuthHandler: func(_ *http.Request) {},
+		MaxRetries:  0,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "2", string(result.NextRow()))
+	assert.Nil(t, result.NextRow())
+	require.NoError(t, result.Err())
+
+	assert.Zero(t, proxied.Load())
+	assert.Equal(t, net.JoinHostPort("localhost", strconv.Itoa(port)), host)
+}
+
+func TestQueryThroughConnectProxyKeepsServerName(t *testing.T) {
+	var serverName string
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverName = r.TLS.ServerName
+
+		w.WriteHeader(http.StatusOK)
+		mustWrite(t, w, analyticsResponse(withResults(3)))
+	}))
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	var connectTarget string
+
+	// The proxy tunnels every CONNECT request to srv, whichever host was asked for.
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		connectTarget = r.Host
+
+		upstream, err := net.Dial("tcp", srv.Listener.Addr().String())
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+
+		conn, _, err := w.(http.Hijacker).Hijack() //nolint:forcetypeassert
+		if err != nil {
+			_ = upstream.Close()
+
+			return
+		}
+
+		go func() {
+			_, _ = io.Copy(upstream, conn)
+			_ = upstream.Close()
+		}()
+
+		_, _ = io.Copy(conn, upstream)
+		_ = conn.Close()
+	}))
+	t.Cleanup(proxy.Close)
+
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+
+	// The httptest certificate is valid for example.com.
+	client := NewClient("https", []Endpoint{{Host: "example.com", Port: 18095}}, ClientConfig{
+		TLSConfig: &tls.Config{ //nolint:exhaustruct
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs, //nolint:forcetypeassert
+		},
+		Logger:         logging.NewDefaultLogger(logging.LogTrace, 0),
+		ConnectTimeout: 5 * time.Second,
+		Transport: TransportConfig{ //nolint:exhaustruct
+			Proxy: NewProxyFunc(proxyURL, ""),
+		},
+		RetryStrategy: nil,
+		WrapTransport: nil,
+	})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	result, err := client.Query(context.Background(), &QueryOptions{
+		Payload:     map[string]interface{}{"statement": "SELECT 3"},
+		AuthHandler: func(_ *http.Request) {},
+		MaxRetries:  0,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "3", string(result.NextRow()))
+	assert.Nil(t, result.NextRow())
+	require.NoError(t, result.Err())
+
+	assert.Equal(t, "example.com:18095", connectTarget)
+	assert.Equal(t, "example.com", serverName)
+}
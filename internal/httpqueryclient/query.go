@@ -6,16 +6,22 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 )
 
+// abandonedQueryCancelTimeout bounds how long is spent cancelling a query on the server after its context
+// has been cancelled.
+const abandonedQueryCancelTimeout = 2 * time.Second
+
 // Query executes a query.
 func (c *Client) Query(ctx context.Context, opts *QueryOptions) (*QueryRowReader, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	statement := getMapValueString(opts.Payload, "statement", "")
+	// The statement is only used in errors, so is redacted up front.
+	statement := c.RedactStatement(getMapValueString(opts.Payload, "statement", ""))
 
 	body, err := json.Marshal(opts.Payload)
 	if err != nil {
@@ -44,14 +50,104 @@ func (c *Client) Query(ctx context.Context, opts *QueryOptions) (*QueryRowReader
 		header:         header,
 		authHandler:    opts.AuthHandler,
 		maxRetries:     opts.MaxRetries,
+		retryStrategy:  opts.RetryStrategy,
 		statement:      statement,
 		payload:        opts.Payload,
 		serverDeadline: serverDeadline,
 	}
 
-	return doWithRetries(ctx, c, reqOpts, func(resp *http.Response, state *retryState) (*QueryRowReader, retryAction, error) {
+	queueTime, err := c.limiter.acquire(ctx)
+	if err != nil {
+		return nil, newAnalyticsError(err, statement, "", 0, 0)
+	}
+
+	reader, err := doWithRetries(ctx, c, reqOpts, func(resp *http.Response, state *retryState) (*QueryRowReader, retryAction, error) {
 		return c.handleQueryResponse(resp, state, statement)
 	})
+
+	clientContextID := getMapValueString(opts.Payload, "client_context_id", "")
+
+	if err != nil {
+		c.limiter.release()
+
+		// The request may have reached the server before the context was cancelled, in which case the query
+		// keeps running there until it times out unless we cancel it.
+		if errors.Is(ctx.Err(), context.Canceled) {
+			c.cancelAbandonedQuery("", clientContextID, opts.AuthHandler)
+		}
+
+		var qErr *QueryError
+		if errors.As(err, &qErr) {
+			qErr.ClientContextID = clientContextID
+
+			// The response body may quote the statement.
+			if c.redaction != RedactionNone {
+				qErr.ErrorText = ""
+			}
+		}
+
+		return nil, err
+	}
+
+	// The request ID is read now as it is no longer available once the stream has failed.
+	requestID := reader.RequestID()
+
+	reader.queueTime = queueTime
+	reader.clientContextID = clientContextID
+	reader.redaction = c.redaction
+	reader.onFinish = c.limiter.release
+	reader.onAbandon = func(closed bool) {
+		if errors.Is(ctx.Err(), context.Canceled) || (closed && opts.CancelOnClose) {
+			c.cancelAbandonedQuery(requestID, clientContextID, opts.AuthHandler)
+		}
+	}
+
+	return reader, nil
+}
+
+// cancelAbandonedQuery cancels a query on the server in the background, after the context for it has been
+// cancelled or its rows have been closed early. This is best effort, any failure is only logged.
+func (c *Client) cancelAbandonedQuery(requestID, clientContextID string, authHandler func(req *http.Request)) {
+	form := url.Values{}
+
+	switch {
+	case requestID != "":
+		form.Set("request_id", requestID)
+	case clientContextID != "":
+		form.Set("client_context_id", clientContextID)
+	default:
+		return
+	}
+
+	c.cleanupLock.Lock()
+	if c.closed {
+		c.cleanupLock.Unlock()
+		c.logger.Debug("Not cancelling abandoned query %s, the client is closed", form.Encode())
+
+		return
+	}
+
+	c.cleanupWg.Add(1)
+	c.cleanupLock.Unlock()
+
+	go func() {
+		defer c.cleanupWg.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), abandonedQueryCancelTimeout)
+		defer cancel()
+
+		_, err := c.doHandleRequest(ctx, handleRequestOptions{
+			method:      "DELETE",
+			path:        "/api/v1/active_requests",
+			body:        []byte(form.Encode()),
+			contentType: "application/x-www-form-urlencoded",
+			authHandler: authHandler,
+			maxRetries:  0,
+		})
+		if err != nil && !isHTTP404Error(err) {
+			c.logger.Debug("Failed to cancel abandoned query %s: %v", form.Encode(), err)
+		}
+	}()
 }
 
 func (c *Client) handleQueryResponse(resp *http.Response, state *retryState, statement string) (*QueryRowReader, retryAction, error) {
@@ -65,11 +161,11 @@ func (c *Client) handleQueryResponse(resp *http.Response, state *retryState, sta
 
 		if readErr != nil {
 			return nil, retryActionReturn, newAnalyticsError(newObfuscateErrorWrapper("failed to read response body", readErr), statement,
-				c.host, resp.StatusCode, state.retries).
+				state.endpointName, resp.StatusCode, state.retries).
 				withErrorText(string(respBody))
 		}
 
-		cErr := parseAnalyticsErrorResponse(respBody, statement, c.host, resp.StatusCode, state.lastCode, state.lastMessage, state.retries)
+		cErr := parseAnalyticsErrorResponse(respBody, statement, state.endpointName, resp.StatusCode, state.lastCode, state.lastMessage, state.retries)
 		if cErr != nil {
 			first, retriable := isAnalyticsErrorRetriable(cErr)
 			if !retriable {
@@ -84,16 +180,17 @@ func (c *Client) handleQueryResponse(resp *http.Response, state *retryState, sta
 			}
 
 			// Return the enriched error in case retry is denied.
-			return nil, retryActionRetry, newAnalyticsError(cErr.InnerError, statement, c.host, resp.StatusCode, state.retries).
+			return nil, retryActionRetry, newAnalyticsError(cErr.InnerError, statement, state.endpointName, resp.StatusCode, state.retries).
 				withErrors(cErr.Errors).
 				withErrorText(string(respBody)).
-				withLastDetail(state.lastCode, state.lastMessage)
+				withLastDetail(state.lastCode, state.lastMessage).
+				withRequestID(cErr.RequestID)
 		}
 
 		return nil, retryActionReturn, newAnalyticsError(
 			errors.New("query returned non-200 status code but no errors in body"), //nolint:err113
 			statement,
-			c.host,
+			state.endpointName,
 			resp.StatusCode,
 			state.retries).
 			withErrorText(string(respBody)).
@@ -114,20 +211,22 @@ func (c *Client) handleQueryResponse(resp *http.Response, state *retryState, sta
 
 		return nil, retryActionReturn, newAnalyticsError(newObfuscateErrorWrapper("failed to parse success response body", err),
 			statement,
-			c.host,
+			state.endpointName,
 			resp.StatusCode,
 			state.retries).
 			withErrorText(string(respBody)).
 			withLastDetail(state.lastCode, state.lastMessage)
 	}
 
+	setSpanRequestID(state.span, streamer)
+
 	peeked := streamer.NextRow()
 	if peeked == nil {
 		err := streamer.Err()
 		if err != nil {
 			return nil, retryActionReturn, newAnalyticsError(err,
 				statement,
-				c.host,
+				state.endpointName,
 				resp.StatusCode,
 				state.retries).
 				withLastDetail(state.lastCode, state.lastMessage)
@@ -137,14 +236,14 @@ func (c *Client) handleQueryResponse(resp *http.Response, state *retryState, sta
 		if metaErr != nil {
 			return nil, retryActionReturn, newAnalyticsError(metaErr,
 				statement,
-				c.host,
+				state.endpointName,
 				resp.StatusCode,
 				state.retries).
 				withErrorText(string(meta)).
 				withLastDetail(state.lastCode, state.lastMessage)
 		}
 
-		cErr := parseAnalyticsErrorResponse(meta, statement, c.host, resp.StatusCode, state.lastCode, state.lastMessage, state.retries)
+		cErr := parseAnalyticsErrorResponse(meta, statement, state.endpointName, resp.StatusCode, state.lastCode, state.lastMessage, state.retries)
 		if cErr != nil {
 			first, retriable := isAnalyticsErrorRetriable(cErr)
 			if !retriable {
@@ -159,19 +258,28 @@ func (c *Client) handleQueryResponse(resp *http.Response, state *retryState, sta
 			}
 
 			// Return the enriched error in case retry is denied.
-			return nil, retryActionRetry, newAnalyticsError(cErr.InnerError, statement, c.host, resp.StatusCode, state.retries).
+			return nil, retryActionRetry, newAnalyticsError(cErr.InnerError, statement, state.endpointName, resp.StatusCode, state.retries).
 				withErrors(cErr.Errors).
 				withErrorText(string(meta)).
-				withLastDetail(state.lastCode, state.lastMessage)
+				withLastDetail(state.lastCode, state.lastMessage).
+				withRequestID(cErr.RequestID)
 		}
 	}
 
 	return &QueryRowReader{
 		streamer:   streamer,
 		statement:  statement,
-		endpoint:   c.host,
+		endpoint:   state.endpointName,
 		statusCode: resp.StatusCode,
 		peeked:     peeked,
+		queueTime:  0,
+
+		clientContextID: "",
+		redaction:       RedactionNone,
+
+		ended:     false,
+		onAbandon: nil,
+		onFinish:  nil,
 	}, retryActionReturn, nil
 }
 
@@ -197,7 +305,8 @@ func parseAnalyticsErrorResponse(respBody []byte, statement, endpoint string, st
 
 	if len(rawRespParse.Errors) == 0 {
 		if statusCode == 503 {
-			return newAnalyticsError(ErrServiceUnavailable, statement, endpoint, statusCode, retries)
+			return newAnalyticsError(ErrServiceUnavailable, statement, endpoint, statusCode, retries).
+				withRequestID(rawRespParse.RequestID)
 		}
 
 		return nil
@@ -215,7 +324,8 @@ func parseAnalyticsErrorResponse(respBody []byte, statement, endpoint string, st
 			retries,
 		).
 			withLastDetail(lastCode, lastMsg).
-			withErrorText(string(respBody))
+			withErrorText(string(respBody)).
+			withRequestID(rawRespParse.RequestID)
 	}
 
 	if len(respParse) == 0 {
@@ -234,7 +344,8 @@ func parseAnalyticsErrorResponse(respBody []byte, statement, endpoint string, st
 	return newAnalyticsError(ErrAnalytics, statement, endpoint, statusCode, retries).
 		withLastDetail(lastCode, lastMsg).
 		withErrorText(string(respBody)).
-		withErrors(errDescs)
+		withErrors(errDescs).
+		withRequestID(rawRespParse.RequestID)
 }
 
 func isAnalyticsErrorRetriable(cErr *QueryError) (*ErrorDesc, bool) {
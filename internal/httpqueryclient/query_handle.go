@@ -16,6 +16,7 @@ var ErrQueryNotFound = errors.New("not found")
 type handleResponse struct {
 	statusCode int
 	body       []byte
+	endpoint   string
 }
 
 type handleRequestOptions struct {
@@ -43,10 +44,11 @@ func (c *Client) handleResponseHandler(resp *http.Response, state *retryState) (
 		return &handleResponse{
 			statusCode: resp.StatusCode,
 			body:       respBody,
+			endpoint:   state.endpointName,
 		}, retryActionReturn, nil
 	}
 
-	cErr := parseAnalyticsErrorResponse(respBody, "", c.host, resp.StatusCode, state.lastCode, state.lastMessage, state.retries)
+	cErr := parseAnalyticsErrorResponse(respBody, "", state.endpointName, resp.StatusCode, state.lastCode, state.lastMessage, state.retries)
 	if cErr != nil {
 		first, retriable := isAnalyticsErrorRetriable(cErr)
 		if !retriable {
@@ -61,13 +63,13 @@ func (c *Client) handleResponseHandler(resp *http.Response, state *retryState) (
 		}
 
 		// Return the enriched error in case retry is denied.
-		return nil, retryActionRetry, newAnalyticsError(cErr.InnerError, "", c.host, resp.StatusCode, state.retries).
+		return nil, retryActionRetry, newAnalyticsError(cErr.InnerError, "", state.endpointName, resp.StatusCode, state.retries).
 			withErrors(cErr.Errors).
 			withErrorText(string(respBody)).
 			withLastDetail(state.lastCode, state.lastMessage)
 	}
 
-	return nil, retryActionReturn, newAnalyticsError(ErrAnalytics, "", c.host, resp.StatusCode, state.retries).
+	return nil, retryActionReturn, newAnalyticsError(ErrAnalytics, "", state.endpointName, resp.StatusCode, state.retries).
 		withErrorText(string(respBody))
 }
 
@@ -84,6 +86,7 @@ func (c *Client) doHandleRequest(ctx context.Context, opts handleRequestOptions)
 		header:         header,
 		authHandler:    opts.authHandler,
 		maxRetries:     opts.maxRetries,
+		retryStrategy:  nil,
 		statement:      "",
 		payload:        nil,
 		serverDeadline: time.Time{},
@@ -92,9 +95,10 @@ func (c *Client) doHandleRequest(ctx context.Context, opts handleRequestOptions)
 	return doWithRetries(ctx, c, reqOpts, c.handleResponseHandler)
 }
 
-// FetchHandleStatus fetches the status of a query handle.
+// FetchHandleStatus fetches the status of a query handle, returning the response body along with the
+// endpoint that it was received from.
 func (c *Client) FetchHandleStatus(ctx context.Context, handle string,
-	authHandler func(req *http.Request), maxRetries uint32) ([]byte, error) {
+	authHandler func(req *http.Request), maxRetries uint32) (body []byte, endpoint string, err error) {
 	resp, err := c.doHandleRequest(ctx, handleRequestOptions{
 		method:      "GET",
 		path:        handle,
@@ -104,10 +108,10 @@ func (c *Client) FetchHandleStatus(ctx context.Context, handle string,
 		maxRetries:  maxRetries,
 	})
 	if err != nil {
-		return nil, maybeQueryNotFoundError(err)
+		return nil, "", maybeQueryNotFoundError(err)
 	}
 
-	return resp.body, nil
+	return resp.body, resp.endpoint, nil
 }
 
 // DiscardHandleResults discards the results for a query handle.
@@ -161,6 +165,7 @@ func (c *Client) StreamHandleResults(ctx context.Context, handle string,
 		path:           handle,
 		authHandler:    authHandler,
 		maxRetries:     maxRetries,
+		retryStrategy:  nil,
 		body:           nil,
 		header:         nil,
 		statement:      "",
@@ -186,7 +191,7 @@ func (c *Client) handleStreamHandleResponse(resp *http.Response, state *retrySta
 			return nil, retryActionReturn, newObfuscateErrorWrapper("failed to read response body", readErr)
 		}
 
-		cErr := parseAnalyticsErrorResponse(respBody, "", c.host, resp.StatusCode, state.lastCode, state.lastMessage, state.retries)
+		cErr := parseAnalyticsErrorResponse(respBody, "", state.endpointName, resp.StatusCode, state.lastCode, state.lastMessage, state.retries)
 		if cErr != nil {
 			first, retriable := isAnalyticsErrorRetriable(cErr)
 			if !retriable {
@@ -200,18 +205,18 @@ func (c *Client) handleStreamHandleResponse(resp *http.Response, state *retrySta
 				state.lastMessage = first.Message
 			}
 
-			return nil, retryActionRetry, newAnalyticsError(cErr.InnerError, "", c.host, resp.StatusCode, state.retries).
+			return nil, retryActionRetry, newAnalyticsError(cErr.InnerError, "", state.endpointName, resp.StatusCode, state.retries).
 				withErrors(cErr.Errors).
 				withErrorText(string(respBody)).
 				withLastDetail(state.lastCode, state.lastMessage)
 		}
 
 		if resp.StatusCode == 404 {
-			return nil, retryActionReturn, newAnalyticsError(ErrQueryNotFound, "", c.host, resp.StatusCode, state.retries).
+			return nil, retryActionReturn, newAnalyticsError(ErrQueryNotFound, "", state.endpointName, resp.StatusCode, state.retries).
 				withErrorText(string(respBody))
 		}
 
-		return nil, retryActionReturn, newAnalyticsError(ErrAnalytics, "", c.host, resp.StatusCode, state.retries).
+		return nil, retryActionReturn, newAnalyticsError(ErrAnalytics, "", state.endpointName, resp.StatusCode, state.retries).
 			withErrorText(string(respBody))
 	}
 
@@ -225,9 +230,14 @@ func (c *Client) handleStreamHandleResponse(resp *http.Response, state *retrySta
 	return &QueryRowReader{
 		streamer:   streamer,
 		statement:  "",
-		endpoint:   c.host,
+		endpoint:   state.endpointName,
 		statusCode: resp.StatusCode,
 		peeked:     nil,
+		queueTime:  0,
+
+		ended:     false,
+		onAbandon: nil,
+		onFinish:  nil,
 	}, retryActionReturn, nil
 }
 
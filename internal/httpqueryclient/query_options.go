@@ -12,4 +12,11 @@ type QueryOptions struct {
 
 	// MaxRetries specifies the maximum number of retries that a query will be attempted.
 	MaxRetries uint32
+
+	// RetryStrategy overrides the client level retry strategy for this query.
+	RetryStrategy RetryStrategy
+
+	// CancelOnClose specifies whether the query is cancelled on the server when the reader is closed before
+	// every row has been read.
+	CancelOnClose bool
 }
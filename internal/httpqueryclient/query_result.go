@@ -1,5 +1,10 @@
 package httpqueryclient
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // QueryRowReader providers access to the rows of an analytics query
 type QueryRowReader struct {
 	streamer   *queryStreamer
@@ -7,6 +12,21 @@ type QueryRowReader struct {
 	endpoint   string
 	statusCode int
 	peeked     []byte
+	queueTime  time.Duration
+
+	// clientContextID is the client context ID that the query was sent with, which is added to query errors.
+	clientContextID string
+	// redaction specifies whether the response text is removed from query errors.
+	redaction RedactionLevel
+
+	// ended is set once NextRow has returned nil, because the rows have all been read or streaming them failed.
+	ended bool
+
+	// onAbandon is called once if the rows are abandoned, because streaming them fails or the reader is closed
+	// before they have all been read. closed is true in the latter case.
+	onAbandon func(closed bool)
+	// onFinish is called once when the rows have all been read, streaming them fails or the reader is closed.
+	onFinish func()
 }
 
 // NextRow reads the next rows bytes from the stream
@@ -18,7 +38,32 @@ func (q *QueryRowReader) NextRow() []byte {
 		return peeked
 	}
 
-	return q.streamer.NextRow()
+	row := q.streamer.NextRow()
+	if row == nil {
+		q.ended = true
+
+		if q.streamer.Err() != nil {
+			q.abandon(false)
+		}
+
+		q.finish()
+	}
+
+	return row
+}
+
+func (q *QueryRowReader) abandon(closed bool) {
+	if q.onAbandon != nil {
+		q.onAbandon(closed)
+		q.onAbandon = nil
+	}
+}
+
+func (q *QueryRowReader) finish() {
+	if q.onFinish != nil {
+		q.onFinish()
+		q.onFinish = nil
+	}
 }
 
 // Err returns any errors that occurred during streaming.
@@ -35,18 +80,50 @@ func (q *QueryRowReader) Err() error {
 
 	cErr := parseAnalyticsErrorResponse(meta, q.statement, q.endpoint, q.statusCode, 0, "", 0)
 	if cErr != nil {
+		cErr.ClientContextID = q.clientContextID
+
+		if q.redaction != RedactionNone {
+			cErr.ErrorText = ""
+		}
+
 		return cErr
 	}
 
 	return nil
 }
 
+// Signature returns the signature sent by the server before the rows, or nil if there was none.
+func (q *QueryRowReader) Signature() json.RawMessage {
+	return q.streamer.EarlyMetadata("signature")
+}
+
+// RequestID returns the request ID sent by the server before the rows, or an empty string if there was none.
+func (q *QueryRowReader) RequestID() string {
+	return earlyRequestID(q.streamer)
+}
+
+// Endpoint returns the host:port of the endpoint that the response was received from.
+func (q *QueryRowReader) Endpoint() string {
+	return q.endpoint
+}
+
 // MetaData fetches the non-row bytes streamed in the response.
 func (q *QueryRowReader) MetaData() ([]byte, error) {
 	return q.streamer.MetaData()
 }
 
+// QueueTime returns how long the query waited to be sent because the maximum number of queries were in flight.
+func (q *QueryRowReader) QueueTime() time.Duration {
+	return q.queueTime
+}
+
 // Close immediately shuts down the connection
 func (q *QueryRowReader) Close() error {
+	if !q.ended {
+		q.abandon(true)
+	}
+
+	q.finish()
+
 	return q.streamer.Close()
 }
@@ -0,0 +1,225 @@
+package httpqueryclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCancelRecordingServer creates a server which calls handleQuery for query requests and sends the form of
+// any cancel requests to the returned channel.
+func newCancelRecordingServer(t *testing.T, handleQuery http.HandlerFunc) (*httptest.Server, <-chan url.Values) {
+	t.Helper()
+
+	cancels := make(chan url.Values, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete && r.URL.Path == "/api/v1/active_requests" {
+			// ParseForm ignores the body of DELETE requests.
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+
+			form, err := url.ParseQuery(string(body))
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+
+			cancels <- form
+
+			w.WriteHeader(http.StatusOK)
+
+			return
+		}
+
+		// The body must be consumed for the request context to be cancelled when the client disconnects.
+		_, _ = io.ReadAll(r.Body)
+
+		handleQuery(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, cancels
+}
+
+func waitForCancel(t *testing.T, cancels <-chan url.Values) url.Values {
+	t.Helper()
+
+	select {
+	case form := <-cancels:
+		return form
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for cancel request")
+
+		return nil
+	}
+}
+
+func TestQueryCancelledBeforeResponseCancelsOnServer(t *testing.T) {
+	received := make(chan struct{})
+
+	srv, cancels := newCancelRecordingServer(t, func(_ http.ResponseWriter, r *http.Request) {
+		close(received)
+		<-r.Context().Done()
+	})
+
+	client := newTestClient(t, srv.Listener.Addr().String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		<-received
+		cancel()
+	}()
+
+	_, err := client.Query(ctx, &QueryOptions{
+		Payload: map[string]interface{}{
+			"statement":         "SELECT 1",
+			"client_context_id": "ctx-1",
+		},
+		AuthHandler: func(_ *http.Request) {},
+		MaxRetries:  5,
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	form := waitForCancel(t, cancels)
+	assert.Equal(t, "ctx-1", form.Get("client_context_id"))
+	assert.Empty(t, form.Get("request_id"))
+
+	require.NoError(t, client.Close())
+}
+
+func TestQueryCancelledWhileStreamingCancelsOnServer(t *testing.T) {
+	srv, cancels := newCancelRecordingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		mustWrite(t, w, []byte(`{"requestID":"req-1","results":[1,`))
+		w.(http.Flusher).Flush() //nolint:forcetypeassert
+
+		<-r.Context().Done()
+	})
+
+	client := newTestClient(t, srv.Listener.Addr().String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	result, err := client.Query(ctx, &QueryOptions{
+		Payload: map[string]interface{}{
+			"statement":         "SELECT 1",
+			"client_context_id": "ctx-1",
+		},
+		AuthHandler: func(_ *http.Request) {},
+		MaxRetries:  5,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "1", string(result.NextRow()))
+
+	cancel()
+
+	assert.Nil(t, result.NextRow())
+	require.Error(t, result.Err())
+
+	form := waitForCancel(t, cancels)
+	assert.Equal(t, "req-1", form.Get("request_id"))
+
+	require.NoError(t, client.Close())
+}
+
+func TestQueryNotCancelledOnServerWhenNotAbandoned(t *testing.T) {
+	srv, cancels := newCancelRecordingServer(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		mustWrite(t, w, analyticsResponse(withStatus("fatal"), withErrors(nonRetriableError(24000, "syntax error"))))
+	})
+
+	client := newTestClient(t, srv.Listener.Addr().String())
+
+	_, err := client.Query(context.Background(), &QueryOptions{
+		Payload: map[string]interface{}{
+			"statement":         "SELEC 1",
+			"client_context_id": "ctx-1",
+		},
+		AuthHandler: func(_ *http.Request) {},
+		MaxRetries:  5,
+	})
+	require.Error(t, err)
+
+	require.NoError(t, client.Close())
+	assert.Empty(t, cancels)
+}
+
+func TestQueryClosedAfterContextCancelledCancelsOnServer(t *testing.T) {
+	srv, cancels := newCancelRecordingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		mustWrite(t, w, []byte(`{"requestID":"req-1","results":[1,`))
+		w.(http.Flusher).Flush() //nolint:forcetypeassert
+
+		<-r.Context().Done()
+	})
+
+	client := newTestClient(t, srv.Listener.Addr().String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	result, err := client.Query(ctx, &QueryOptions{
+		Payload: map[string]interface{}{
+			"statement":         "SELECT 1",
+			"client_context_id": "ctx-1",
+		},
+		AuthHandler: func(_ *http.Request) {},
+		MaxRetries:  5,
+	})
+	require.NoError(t, err)
+
+	cancel()
+
+	// The rows are abandoned without NextRow seeing the stream fail.
+	_ = result.Close()
+
+	form := waitForCancel(t, cancels)
+	assert.Equal(t, "req-1", form.Get("request_id"))
+
+	require.NoError(t, client.Close())
+}
+
+func TestQueryNotCancelledOnServerAfterClientClosed(t *testing.T) {
+	srv, cancels := newCancelRecordingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		mustWrite(t, w, []byte(`{"requestID":"req-1","results":[1,`))
+		w.(http.Flusher).Flush() //nolint:forcetypeassert
+
+		<-r.Context().Done()
+	})
+
+	client := newTestClient(t, srv.Listener.Addr().String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	result, err := client.Query(ctx, &QueryOptions{
+		Payload: map[string]interface{}{
+			"statement":         "SELECT 1",
+			"client_context_id": "ctx-1",
+		},
+		AuthHandler: func(_ *http.Request) {},
+		MaxRetries:  5,
+	})
+	require.NoError(t, err)
+
+	cancel()
+	require.NoError(t, client.Close())
+
+	_ = result.Close()
+
+	assert.Empty(t, cancels)
+}
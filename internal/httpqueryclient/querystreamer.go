@@ -76,9 +76,38 @@ func (r *queryStreamer) Err() error {
 
 // EarlyMetadata returns the value (or nil) of an attribute from a query metadata before the query has completed.
 func (r *queryStreamer) EarlyMetadata(key string) json.RawMessage {
+	if r.streamer == nil {
+		// Once the stream has finished every attribute is available from the meta-data instead.
+		if r.metaDataBytes == nil {
+			return nil
+		}
+
+		var attribs map[string]json.RawMessage
+		if err := json.Unmarshal(r.metaDataBytes, &attribs); err != nil {
+			return nil
+		}
+
+		return attribs[key]
+	}
+
 	return r.streamer.EarlyAttrib(key)
 }
 
+// earlyRequestID returns the request ID from the query metadata, or an empty string if it is not available.
+func earlyRequestID(streamer *queryStreamer) string {
+	raw := streamer.EarlyMetadata("requestID")
+	if raw == nil {
+		return ""
+	}
+
+	var requestID string
+	if err := json.Unmarshal(raw, &requestID); err != nil {
+		return ""
+	}
+
+	return requestID
+}
+
 func (r *queryStreamer) finishWithoutError() {
 	// Let's finalize the streamer so we Get the meta-data
 	metaDataBytes, err := r.streamer.Finalize()
@@ -0,0 +1,168 @@
+package httpqueryclient
+
+import (
+	"strings"
+)
+
+// RedactionLevel specifies how much of a statement is removed before it is included in errors, logs and spans.
+type RedactionLevel int
+
+const (
+	// RedactionNone includes statements as they are.
+	RedactionNone RedactionLevel = iota
+
+	// RedactionPartial replaces the literals in statements with ?, keeping their structure.
+	RedactionPartial
+
+	// RedactionFull omits statements entirely.
+	RedactionFull
+)
+
+// RedactStatement returns the statement redacted according to the redaction level of the client.
+func (c *Client) RedactStatement(statement string) string {
+	return RedactStatement(c.redaction, statement)
+}
+
+// RedactStatement returns statement redacted according to level.
+func RedactStatement(level RedactionLevel, statement string) string {
+	switch level {
+	case RedactionPartial:
+		return stripLiterals(statement)
+	case RedactionFull:
+		return ""
+	default:
+		return statement
+	}
+}
+
+// stripLiterals replaces the string and numeric literals in a SQL++ statement with ?, and removes comments.
+// Identifiers, including those quoted with backticks, keywords and parameters such as $1 and $name are kept.
+// Unterminated literals and comments are stripped to the end of the statement.
+func stripLiterals(statement string) string {
+	var sb strings.Builder
+
+	sb.Grow(len(statement))
+
+	for i := 0; i < len(statement); {
+		c := statement[i]
+
+		switch {
+		case c == '\'' || c == '"':
+			i = skipQuoted(statement, i, c)
+
+			sb.WriteByte('?')
+		case c == '`':
+			end := skipQuoted(statement, i, c)
+
+			sb.WriteString(statement[i:end])
+
+			i = end
+		case c == '-' && strings.HasPrefix(statement[i:], "--"):
+			end := strings.IndexByte(statement[i:], '\n')
+			if end < 0 {
+				i = len(statement)
+			} else {
+				i += end
+			}
+		case c == '/' && strings.HasPrefix(statement[i:], "/*"):
+			end := strings.Index(statement[i+2:], "*/")
+			if end < 0 {
+				i = len(statement)
+			} else {
+				i += end + 4
+			}
+
+			sb.WriteByte(' ')
+		case isIdentifierChar(c):
+			start := i
+			for i < len(statement) && isIdentifierChar(statement[i]) {
+				i++
+			}
+
+			// An identifier or parameter may contain digits, but a number must start with one.
+			if isDigit(c) {
+				i = skipNumber(statement, start)
+
+				sb.WriteByte('?')
+			} else {
+				sb.WriteString(statement[start:i])
+			}
+		case c == '.' && i+1 < len(statement) && isDigit(statement[i+1]) &&
+			(i == 0 || !isIdentifierChar(statement[i-1]) && statement[i-1] != '`' && statement[i-1] != ')' &&
+				statement[i-1] != ']'):
+			i = skipNumber(statement, i)
+
+			sb.WriteByte('?')
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+
+	return sb.String()
+}
+
+// skipQuoted returns the index after the quoted string or identifier starting at start. The quote is escaped
+// either by a backslash or by being doubled.
+func skipQuoted(statement string, start int, quote byte) int {
+	for i := start + 1; i < len(statement); i++ {
+		switch statement[i] {
+		case '\\':
+			i++
+		case quote:
+			if i+1 < len(statement) && statement[i+1] == quote {
+				i++
+
+				continue
+			}
+
+			return i + 1
+		}
+	}
+
+	return len(statement)
+}
+
+// skipNumber returns the index after the numeric literal starting at start, such as 12, 1.5, .5 or 1e-3.
+func skipNumber(statement string, start int) int {
+	i := start
+	for i < len(statement) && isDigit(statement[i]) {
+		i++
+	}
+
+	if i < len(statement) && statement[i] == '.' {
+		i++
+		for i < len(statement) && isDigit(statement[i]) {
+			i++
+		}
+	}
+
+	if i < len(statement) && (statement[i] == 'e' || statement[i] == 'E') {
+		j := i + 1
+		if j < len(statement) && (statement[j] == '+' || statement[j] == '-') {
+			j++
+		}
+
+		if j < len(statement) && isDigit(statement[j]) {
+			i = j
+			for i < len(statement) && isDigit(statement[i]) {
+				i++
+			}
+		}
+	}
+
+	// Anything directly after the number, such as a type suffix, is part of the literal.
+	for i < len(statement) && isIdentifierChar(statement[i]) {
+		i++
+	}
+
+	return i
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
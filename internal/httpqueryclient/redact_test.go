@@ -0,0 +1,77 @@
+package httpqueryclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripLiterals(t *testing.T) {
+	tests := []struct {
+		statement string
+		expected  string
+	}{
+		{"SELECT 1", "SELECT ?"},
+		{"SELECT * FROM customers WHERE name = 'Jane Doe'", "SELECT * FROM customers WHERE name = ?"},
+		{`SELECT * FROM customers WHERE name = "Jane Doe"`, "SELECT * FROM customers WHERE name = ?"},
+		{"SELECT * FROM t WHERE s = 'it''s' AND u = 'a\\'b'", "SELECT * FROM t WHERE s = ? AND u = ?"},
+		{"SELECT * FROM `my-db`.`scope``1`.t1 WHERE t1.id = 42", "SELECT * FROM `my-db`.`scope``1`.t1 WHERE t1.id = ?"},
+		{"SELECT x FROM t WHERE x > 1.5e-3 AND y < .25 AND z = -7", "SELECT x FROM t WHERE x > ? AND y < ? AND z = -?"},
+		{"SELECT $1, $name, ? FROM t", "SELECT $1, $name, ? FROM t"},
+		{"SELECT a[0], {\"k\": 'v'} FROM t", "SELECT a[?], {?: ?} FROM t"},
+		{"SELECT 1 -- customer 12345\nFROM t", "SELECT ? \nFROM t"},
+		{"SELECT /* id 'x' */ 2", "SELECT   ?"},
+		{"SELECT 'unterminated", "SELECT ?"},
+		{"SELECT TRUE, NULL, MISSING", "SELECT TRUE, NULL, MISSING"},
+		{"SELECT 'ünïcode' AS é", "SELECT ? AS é"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.statement, func(tt *testing.T) {
+			assert.Equal(tt, test.expected, stripLiterals(test.statement))
+		})
+	}
+}
+
+func TestRedactStatement(t *testing.T) {
+	assert.Equal(t, "SELECT 'x'", RedactStatement(RedactionNone, "SELECT 'x'"))
+	assert.Equal(t, "SELECT ?", RedactStatement(RedactionPartial, "SELECT 'x'"))
+	assert.Empty(t, RedactStatement(RedactionFull, "SELECT 'x'"))
+}
+
+func TestQueryErrorRedacted(t *testing.T) {
+	body := analyticsResponse(
+		withStatus("fatal"),
+		withErrors(nonRetriableError(24000, "Syntax error")),
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(400)
+		mustWrite(t, w, body)
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv.Listener.Addr().String())
+	defer client.Close()
+
+	client.redaction = RedactionPartial
+
+	_, err := client.Query(context.Background(), &QueryOptions{
+		Payload:     map[string]interface{}{"statement": "SELECT * FROM t WHERE ssn = '123-45-6789'"},
+		AuthHandler: func(_ *http.Request) {},
+		MaxRetries:  0,
+	})
+	require.Error(t, err)
+
+	var qErr *QueryError
+
+	require.True(t, errors.As(err, &qErr))
+	assert.Equal(t, "SELECT * FROM t WHERE ssn = ?", qErr.Statement)
+	assert.Empty(t, qErr.ErrorText)
+	assert.NotContains(t, err.Error(), "123-45-6789")
+}
@@ -8,8 +8,10 @@ import (
 	"io"
 	"math"
 	"math/rand"
+	"net"
 	"net/http"
 	"net/http/httptrace"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -26,6 +28,9 @@ type retryableRequestOptions struct {
 	authHandler func(req *http.Request)
 	maxRetries  uint32
 
+	// retryStrategy overrides the client level retry strategy when set.
+	retryStrategy RetryStrategy
+
 	// statement and payload are used for query-specific retry logic (timeout recalculation).
 	// They can be left empty for non-query requests.
 	statement      string
@@ -40,9 +45,20 @@ type retryState struct {
 	lastRootErr error
 	retries     uint32
 	uniqueID    string
-	backoff     backoffCalculator
-	addrs       []string
+	strategy    RetryStrategy
 	body        []byte
+
+	// statement is the statement of the query being sent, already redacted, or empty if the request is not
+	// for a query or the statement is fully redacted.
+	statement string
+
+	// endpoint is the endpoint that the current attempt is being sent to, and endpointName its host:port.
+	endpoint     *endpointState
+	endpointName string
+	addrs        map[*endpointState][]string
+
+	// span is the span for the current attempt.
+	span Span
 }
 
 // retryAction represents what the response handler wants to do.
@@ -65,22 +81,31 @@ func doWithRetries[T any](
 	opts *retryableRequestOptions,
 	handler retryableResponseHandler[T],
 ) (*T, error) {
-	addrs, err := c.resolver.LookupHost(ctx, c.host)
-	if err != nil {
-		return nil, newAnalyticsError(fmt.Errorf("failed to lookup host: %w", err), opts.statement, c.host, 0, 0)
+	strategy := opts.retryStrategy
+	if strategy == nil {
+		strategy = c.retryStrategy
 	}
 
 	state := &retryState{
-		lastCode:    0,
-		lastMessage: "",
-		lastRootErr: nil,
-		retries:     0,
-		uniqueID:    uuid.NewString(),
-		backoff:     analyticsExponentialBackoffWithJitter(100*time.Millisecond, 1*time.Minute, 2),
-		addrs:       addrs,
-		body:        opts.body,
+		lastCode:     0,
+		lastMessage:  "",
+		lastRootErr:  nil,
+		retries:      0,
+		uniqueID:     uuid.NewString(),
+		strategy:     strategy,
+		body:         opts.body,
+		statement:    opts.statement,
+		endpoint:     nil,
+		endpointName: "",
+		addrs:        make(map[*endpointState][]string),
+		span:         noopSpan{},
 	}
 
+	clientContextID := getMapValueString(opts.payload, "client_context_id", "")
+	stats := requestStatsFromContext(ctx)
+
+	c.retryBudget.addRequest()
+
 	for {
 		// We use > here as this check is at the top of the loop, so we want to allow the nth retry to be made.
 		if state.retries > opts.maxRetries {
@@ -88,10 +113,61 @@ func doWithRetries[T any](
 			return nil, state.lastRootErr
 		}
 
-		idx := rand.Intn(len(state.addrs)) //nolint:gosec
-		addr := state.addrs[idx]
+		endpoint := c.endpoints.Select(state.endpoint)
+		if addrs, resolved := state.addrs[endpoint]; resolved && addrs == nil {
+			// This endpoint has already failed lookup during this request, so look for one that hasn't.
+			endpoint = state.unfailedEndpoint(c.endpoints.endpoints)
+			if endpoint == nil {
+				return nil, state.lastRootErr
+			}
+		}
+
+		if !endpoint.breaker.allow() {
+			endpoint = state.circuitAllowedEndpoint(c.endpoints.endpoints, endpoint)
+			if endpoint == nil {
+				return nil, newAnalyticsError(ErrCircuitOpen, opts.statement, state.endpointName, 0, state.retries).
+					withLastDetail(state.lastCode, state.lastMessage)
+			}
+		}
+
+		state.endpoint = endpoint
+		state.endpointName = endpoint.String()
+
+		addrs, resolved := state.addrs[endpoint]
+		if !resolved && (endpoint.proxied || c.skipHostLookup) {
+			// The proxy resolves the host, which may not be resolvable from here. When lookups are skipped the
+			// host is never contacted, so it need not be resolvable at all.
+			addrs = []string{endpoint.Host}
+			state.addrs[endpoint] = addrs
+		} else if !resolved {
+			var err error
+
+			addrs, err = c.resolver.LookupHost(ctx, endpoint.Host)
+			if err != nil {
+				endpoint.breaker.recordAbort()
+
+				// Cache the failure so that this endpoint isn't looked up again for this request.
+				state.addrs[endpoint] = nil
+				state.lastRootErr = newAnalyticsError(fmt.Errorf("failed to lookup host: %w", err), opts.statement,
+					state.endpointName, 0, state.retries)
+
+				if state.unfailedEndpoint(c.endpoints.endpoints) == nil {
+					return nil, state.lastRootErr
+				}
+
+				c.logger.Debug("Failed to lookup host %s, trying another endpoint: %v", endpoint.Host, err)
+				c.endpoints.Quarantine(endpoint)
+
+				continue
+			}
+
+			state.addrs[endpoint] = addrs
+		}
+
+		idx := rand.Intn(len(addrs)) //nolint:gosec
+		addr := addrs[idx]
 
-		reqURI := fmt.Sprintf("%s://%s:%d%s", c.scheme, addr, c.port, opts.path)
+		reqURI := fmt.Sprintf("%s://%s%s", c.scheme, net.JoinHostPort(addr, strconv.Itoa(endpoint.Port)), opts.path)
 
 		var connectDoneErr error
 
@@ -108,10 +184,16 @@ func doWithRetries[T any](
 
 		req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), opts.method, reqURI, reqBody)
 		if err != nil {
+			endpoint.breaker.recordAbort()
+
 			return nil, newObfuscateErrorWrapper("failed to create http request", err)
 		}
 
-		req.Host = c.host
+		// Proxied requests are already addressed to the host. Other requests are addressed to a resolved IP, so
+		// the Host header is set to the host, including the port as it would be for a request addressed to it.
+		if !endpoint.proxied {
+			req.Host = net.JoinHostPort(endpoint.Host, strconv.Itoa(endpoint.Port))
+		}
 
 		if opts.header != nil {
 			req.Header = opts.header
@@ -121,26 +203,27 @@ func doWithRetries[T any](
 			opts.authHandler(req)
 		}
 
-		c.logger.Trace("Sending request %s to %s", state.uniqueID, reqURI)
+		c.logger.Trace("Sending request %s to %s", state.requestDesc(), reqURI)
+
+		state.span = startAttemptSpan(ctx, state, clientContextID)
 
-		resp, err := c.innerClient.Do(req)
+		resp, err := endpoint.innerClient.Do(req)
 		if err != nil {
 			c.logger.Trace("Received HTTP Response for ID=%s, errored: %v", state.uniqueID, err)
 
+			state.span.End()
+
 			// We don't want to bail out on connection errors as they may be because of dial timeout.
 			if connectDoneErr == nil {
 				if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-					return nil, newAnalyticsError(err, opts.statement, c.host, 0, state.retries).
+					endpoint.breaker.recordAbort()
+
+					return nil, newAnalyticsError(err, opts.statement, state.endpointName, 0, state.retries).
 						withLastDetail(state.lastCode, state.lastMessage)
 				}
 			}
 
-			newBody, notRetriableErr := c.handleMaybeRetry(ctx, state.uniqueID, opts.serverDeadline,
-				state.backoff, state.retries, opts.payload)
-			if notRetriableErr != nil {
-				return nil, newAnalyticsError(notRetriableErr, opts.statement, c.host, 0, state.retries).
-					withLastDetail(state.lastCode, state.lastMessage)
-			}
+			endpoint.breaker.recordFailure()
 
 			if connectDoneErr == nil {
 				state.lastRootErr = newObfuscateErrorWrapper("failed to send request", err)
@@ -148,37 +231,92 @@ func doWithRetries[T any](
 				state.lastRootErr = connectDoneErr
 			}
 
+			if connectDoneErr != nil || isDialError(err) {
+				c.logger.Debug("Failed to connect to endpoint %s, quarantining: %v", state.endpointName, err)
+				c.endpoints.Quarantine(endpoint)
+			}
+
+			newBody, notRetriableErr := c.handleMaybeRetry(ctx, state, opts.serverDeadline, opts.payload, &RetryRequest{
+				Attempt:        state.retries,
+				Errors:         nil,
+				TransportError: err,
+				HTTPStatusCode: 0,
+				Deadline:       time.Time{},
+			})
+			if notRetriableErr != nil {
+				if errors.Is(notRetriableErr, errRetryDeclined) {
+					return nil, state.lastRootErr
+				}
+
+				return nil, newAnalyticsError(notRetriableErr, opts.statement, state.endpointName, 0, state.retries).
+					withLastDetail(state.lastCode, state.lastMessage)
+			}
+
 			if newBody != nil {
 				state.body = newBody
 			}
 
 			state.retries++
+			stats.addRetry()
 
 			continue
 		}
 
 		c.logger.Trace("Received HTTP Response for ID=%s, status=%d", state.uniqueID, resp.StatusCode)
 
+		state.span.SetAttribute(SpanAttributeStatusCode, resp.StatusCode)
+
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			c.logger.Debug("Endpoint %s is unavailable, quarantining", state.endpointName)
+			c.endpoints.Quarantine(endpoint)
+			endpoint.breaker.recordFailure()
+		} else {
+			endpoint.markHealthy()
+			endpoint.breaker.recordSuccess()
+		}
+
+		resp.Body = newCountingReadCloser(resp.Body, stats)
 		resp = leakcheck.WrapHTTPResponse(resp) //nolint:bodyclose
 
 		result, action, handlerErr := handler(resp, state)
+
+		state.span.End()
+
 		if action == retryActionRetry {
-			newBody, retryErr := c.handleMaybeRetry(ctx, state.uniqueID, opts.serverDeadline,
-				state.backoff, state.retries, opts.payload)
+			var errDescs []ErrorDesc
+
+			var qErr *QueryError
+			if errors.As(handlerErr, &qErr) {
+				errDescs = qErr.Errors
+			}
+
+			newBody, retryErr := c.handleMaybeRetry(ctx, state, opts.serverDeadline, opts.payload, &RetryRequest{
+				Attempt:        state.retries,
+				Errors:         errDescs,
+				TransportError: nil,
+				HTTPStatusCode: resp.StatusCode,
+				Deadline:       time.Time{},
+			})
 			if retryErr != nil {
+				if errors.Is(retryErr, errRetryDeclined) {
+					// The strategy declined to retry, this is equivalent to retries being exhausted.
+					if handlerErr != nil {
+						return nil, handlerErr
+					}
+
+					return nil, state.lastRootErr
+				}
+
 				// If the handler provided an enriched error, update its inner error to
 				// reflect the retry denial reason (e.g. timeout) so callers can
 				// unwrap the correct cause.
-				if handlerErr != nil {
-					var qErr *QueryError
-					if errors.As(handlerErr, &qErr) {
-						qErr.InnerError = retryErr
+				if qErr != nil {
+					qErr.InnerError = retryErr
 
-						return nil, qErr
-					}
+					return nil, qErr
 				}
 
-				return nil, newAnalyticsError(retryErr, opts.statement, c.host, resp.StatusCode, state.retries).
+				return nil, newAnalyticsError(retryErr, opts.statement, state.endpointName, resp.StatusCode, state.retries).
 					withLastDetail(state.lastCode, state.lastMessage)
 			}
 
@@ -187,6 +325,7 @@ func doWithRetries[T any](
 			}
 
 			state.retries++
+			stats.addRetry()
 
 			continue
 		}
@@ -195,6 +334,54 @@ func doWithRetries[T any](
 	}
 }
 
+// unfailedEndpoint returns an endpoint which has not failed host lookup during this request, or nil if
+// every endpoint has failed.
+func (s *retryState) unfailedEndpoint(endpoints []*endpointState) *endpointState {
+	for _, endpoint := range endpoints {
+		if addrs, resolved := s.addrs[endpoint]; !resolved || addrs != nil {
+			return endpoint
+		}
+	}
+
+	return nil
+}
+
+// requestDesc describes the request in log messages, by its unique ID and the statement if there is one.
+func (s *retryState) requestDesc() string {
+	if s.statement == "" {
+		return s.uniqueID
+	}
+
+	return fmt.Sprintf("%s (statement: %s)", s.uniqueID, s.statement)
+}
+
+// circuitAllowedEndpoint returns an endpoint, other than exclude, whose circuit breaker allows a request to be
+// sent and which has not failed host lookup during this request, or nil if there is none.
+func (s *retryState) circuitAllowedEndpoint(endpoints []*endpointState, exclude *endpointState) *endpointState {
+	for _, endpoint := range endpoints {
+		if endpoint == exclude {
+			continue
+		}
+
+		if addrs, resolved := s.addrs[endpoint]; resolved && addrs == nil {
+			continue
+		}
+
+		if endpoint.breaker.allow() {
+			return endpoint
+		}
+	}
+
+	return nil
+}
+
+// isDialError returns true if the error occurred whilst establishing a connection.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
 // newResettableReader creates a new reader from a byte slice. This is useful so that
 // the request body can be re-read on retries.
 func newResettableReader(data []byte) io.Reader {
@@ -219,11 +406,23 @@ func (r *resettableReader) Read(p []byte) (n int, err error) {
 
 // handleMaybeRetry checks whether a retry should be performed and sleeps for the backoff duration.
 // Note in the interest of keeping this signature sane, we return a raw base error here.
-func (c *Client) handleMaybeRetry(ctx context.Context, reqID string, serverDeadline time.Time, calc backoffCalculator,
-	retries uint32, payload map[string]interface{}) ([]byte, error) {
-	b := calc(retries)
+// errRetryDeclined is returned when the retry strategy decides that the request should not be retried.
+func (c *Client) handleMaybeRetry(ctx context.Context, state *retryState, serverDeadline time.Time,
+	payload map[string]interface{}, req *RetryRequest) ([]byte, error) {
 	ctxDeadline, _ := ctx.Deadline()
 
+	req.Deadline = ctxDeadline
+	if !serverDeadline.IsZero() && (req.Deadline.IsZero() || serverDeadline.Before(req.Deadline)) {
+		req.Deadline = serverDeadline
+	}
+
+	b, shouldRetry := state.strategy.RetryAfter(req)
+	if !shouldRetry {
+		c.logger.Trace("Retry strategy declined to retry request %s, retries: %d", state.requestDesc(), state.retries)
+
+		return nil, errRetryDeclined
+	}
+
 	var body []byte
 
 	if !ctxDeadline.IsZero() {
@@ -250,7 +449,14 @@ func (c *Client) handleMaybeRetry(ctx context.Context, reqID string, serverDeadl
 		body = payloadBody
 	}
 
-	c.logger.Trace("Retrying request %s in %s, retries: %d", reqID, b, retries)
+	// The budget is checked last, so that it is only used by retries which are going to be made.
+	if !c.retryBudget.tryRetry() {
+		c.logger.Debug("Retry budget exhausted, not retrying request %s, retries: %d", state.requestDesc(), state.retries)
+
+		return nil, errRetryDeclined
+	}
+
+	c.logger.Trace("Retrying request %s in %s, retries: %d", state.requestDesc(), b, state.retries)
 
 	select {
 	case <-ctx.Done():
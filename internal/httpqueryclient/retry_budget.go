@@ -0,0 +1,118 @@
+package httpqueryclient
+
+import (
+	"sync"
+	"time"
+)
+
+// retryBudgetBuckets is the number of buckets that the retry budget window is divided into.
+const retryBudgetBuckets = 10
+
+// RetryBudgetConfig holds the configuration for limiting retries across all requests made by a client.
+type RetryBudgetConfig struct {
+	// Enabled specifies whether retries are limited by the budget.
+	Enabled bool
+
+	// Ratio is the number of retries allowed for each request made within Window, for example 0.2 allows
+	// retries to add up to 20% to the number of requests.
+	Ratio float64
+
+	// MinRetries is the number of retries allowed within Window regardless of the number of requests, so that
+	// clients making few requests can still retry.
+	MinRetries int
+
+	// Window is the period over which requests and retries are counted.
+	Window time.Duration
+}
+
+type retryBudgetBucket struct {
+	start    time.Time
+	requests int
+	retries  int
+}
+
+// retryBudget limits the number of retries made, across all requests, to a proportion of the requests made
+// recently. This stops retries from multiplying the load on the server during an outage.
+type retryBudget struct {
+	ratio          float64
+	minRetries     int
+	bucketDuration time.Duration
+
+	lock    sync.Mutex
+	buckets [retryBudgetBuckets]retryBudgetBucket
+}
+
+func newRetryBudget(config RetryBudgetConfig) *retryBudget {
+	if !config.Enabled {
+		return nil
+	}
+
+	bucketDuration := config.Window / retryBudgetBuckets
+	if bucketDuration <= 0 {
+		bucketDuration = time.Millisecond
+	}
+
+	return &retryBudget{
+		ratio:          config.Ratio,
+		minRetries:     config.MinRetries,
+		bucketDuration: bucketDuration,
+		lock:           sync.Mutex{},
+		buckets:        [retryBudgetBuckets]retryBudgetBucket{},
+	}
+}
+
+// bucket returns the bucket for now, resetting it if it was last used for an earlier window.
+// The lock must be held.
+func (b *retryBudget) bucket(now time.Time) *retryBudgetBucket {
+	start := now.Truncate(b.bucketDuration)
+	bucket := &b.buckets[(start.UnixNano()/int64(b.bucketDuration))%retryBudgetBuckets]
+
+	if !bucket.start.Equal(start) {
+		bucket.start = start
+		bucket.requests = 0
+		bucket.retries = 0
+	}
+
+	return bucket
+}
+
+// addRequest records that a request has been made. A nil budget records nothing.
+func (b *retryBudget) addRequest() {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	b.bucket(time.Now()).requests++
+	b.lock.Unlock()
+}
+
+// tryRetry records a retry and returns true if the budget allows it. A nil budget allows every retry.
+func (b *retryBudget) tryRetry() bool {
+	if b == nil {
+		return true
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := time.Now()
+	windowStart := now.Add(-b.bucketDuration * (retryBudgetBuckets - 1)).Truncate(b.bucketDuration)
+
+	var requests, retries int
+
+	for _, bucket := range b.buckets {
+		if !bucket.start.Before(windowStart) {
+			requests += bucket.requests
+			retries += bucket.retries
+		}
+	}
+
+	if float64(retries) >= float64(b.minRetries)+b.ratio*float64(requests) {
+		return false
+	}
+
+	b.bucket(now).retries++
+
+	return true
+}
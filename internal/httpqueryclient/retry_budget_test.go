@@ -0,0 +1,136 @@
+package httpqueryclient
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/couchbase/gocbanalytics/internal/logging"
+)
+
+func TestRetryBudgetDisabled(t *testing.T) {
+	budget := newRetryBudget(RetryBudgetConfig{Enabled: false, Ratio: 0, MinRetries: 0, Window: time.Second})
+	assert.Nil(t, budget)
+
+	budget.addRequest()
+	assert.True(t, budget.tryRetry())
+}
+
+func TestRetryBudgetRatio(t *testing.T) {
+	budget := newRetryBudget(RetryBudgetConfig{Enabled: true, Ratio: 0.5, MinRetries: 1, Window: time.Minute})
+
+	for range 4 {
+		budget.addRequest()
+	}
+
+	// One retry is always allowed, plus one for every two requests.
+	for range 3 {
+		assert.True(t, budget.tryRetry())
+	}
+
+	assert.False(t, budget.tryRetry())
+
+	budget.addRequest()
+	budget.addRequest()
+
+	assert.True(t, budget.tryRetry())
+	assert.False(t, budget.tryRetry())
+}
+
+func TestRetryBudgetWindow(t *testing.T) {
+	budget := newRetryBudget(RetryBudgetConfig{Enabled: true, Ratio: 0, MinRetries: 1, Window: 50 * time.Millisecond})
+
+	assert.True(t, budget.tryRetry())
+	assert.False(t, budget.tryRetry())
+
+	// Once the retry has left the window the budget is available again.
+	require.Eventually(t, budget.tryRetry, time.Second, 5*time.Millisecond)
+}
+
+func TestQueryRetries_RetryBudgetExhausted(t *testing.T) {
+	var attempt int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&attempt, 1)
+		w.WriteHeader(200)
+		mustWrite(t, w, analyticsResponse(
+			withStatus("fatal"),
+			withErrors(retriableError(23001, "temporarily unavailable")),
+		))
+	}))
+	defer srv.Close()
+
+	host, portStr, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+
+	port, err := strconv.Atoi(portStr)
+	require.NoError(t, err)
+
+	client := NewClient("http", []Endpoint{{Host: host, Port: port}}, ClientConfig{ //nolint:exhaustruct
+		Logger:         logging.NewDefaultLogger(logging.LogTrace, 0),
+		ConnectTimeout: 5 * time.Second,
+		RetryBudget: RetryBudgetConfig{
+			Enabled:    true,
+			Ratio:      0,
+			MinRetries: 2,
+			Window:     time.Minute,
+		},
+	})
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err = client.Query(ctx, &QueryOptions{
+		Payload:     map[string]interface{}{"statement": "SELECT 1"},
+		AuthHandler: func(_ *http.Request) {},
+		MaxRetries:  10,
+	})
+	require.Error(t, err)
+
+	// The first attempt plus the two retries allowed by the budget.
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempt))
+
+	var qErr *QueryError
+
+	require.ErrorAs(t, err, &qErr)
+	assert.Equal(t, uint32(2), qErr.Retries)
+}
+
+func TestRetryBudgetNotUsedWhenRetryRefused(t *testing.T) {
+	client := NewClient("http", []Endpoint{{Host: "127.0.0.1", Port: 1}}, ClientConfig{ //nolint:exhaustruct
+		Logger:         logging.NewDefaultLogger(logging.LogTrace, 0),
+		ConnectTimeout: 5 * time.Second,
+		RetryBudget: RetryBudgetConfig{
+			Enabled:    true,
+			Ratio:      0,
+			MinRetries: 1,
+			Window:     time.Minute,
+		},
+	})
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	state := &retryState{ //nolint:exhaustruct
+		uniqueID: "req-1",
+		strategy: NewExponentialBackoffRetryStrategy(time.Minute, time.Minute, 2),
+	}
+
+	// The backoff would exceed the context deadline, so the retry is refused.
+	_, err := client.handleMaybeRetry(ctx, state, time.Time{}, nil, &RetryRequest{}) //nolint:exhaustruct
+	require.ErrorIs(t, err, ErrContextDeadlineWouldBeExceeded)
+
+	// The single retry allowed by the budget is still available.
+	assert.True(t, client.retryBudget.tryRetry())
+	assert.False(t, client.retryBudget.tryRetry())
+}
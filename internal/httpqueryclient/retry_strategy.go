@@ -0,0 +1,57 @@
+package httpqueryclient
+
+import (
+	"errors"
+	"time"
+)
+
+// errRetryDeclined is returned internally when a RetryStrategy decides not to retry a request.
+var errRetryDeclined = errors.New("retry strategy declined to retry")
+
+// RetryRequest describes a failed, retriable, request attempt.
+type RetryRequest struct {
+	// Attempt is the number of retries that have already been made for this request.
+	Attempt uint32
+
+	// Errors contains any errors returned in the response body by the server.
+	Errors []ErrorDesc
+
+	// TransportError is the error returned when the request could not be sent, or no response was received.
+	TransportError error
+
+	// HTTPStatusCode is the status code of the response, or 0 if no response was received.
+	HTTPStatusCode int
+
+	// Deadline is the point in time at which the request will time out, or the zero time if there is no deadline.
+	Deadline time.Time
+}
+
+// RetryStrategy decides whether a failed, retriable, request should be retried.
+type RetryStrategy interface {
+	// RetryAfter returns the duration to wait before retrying the request, and whether the request should
+	// be retried at all.
+	RetryAfter(req *RetryRequest) (time.Duration, bool)
+}
+
+// BackoffRetryStrategy is a RetryStrategy that always retries, waiting for the duration returned by a
+// backoff calculator.
+type BackoffRetryStrategy struct {
+	calc backoffCalculator
+}
+
+// NewExponentialBackoffRetryStrategy creates a new BackoffRetryStrategy which uses exponential backoff with jitter.
+func NewExponentialBackoffRetryStrategy(minBackoff, maxBackoff time.Duration, backoffFactor float64) *BackoffRetryStrategy {
+	return &BackoffRetryStrategy{
+		calc: analyticsExponentialBackoffWithJitter(minBackoff, maxBackoff, backoffFactor),
+	}
+}
+
+// RetryAfter returns the backoff for the given request attempt.
+func (s *BackoffRetryStrategy) RetryAfter(req *RetryRequest) (time.Duration, bool) {
+	return s.calc(req.Attempt), true
+}
+
+// NewDefaultRetryStrategy creates the RetryStrategy used when none is specified.
+func NewDefaultRetryStrategy() RetryStrategy {
+	return NewExponentialBackoffRetryStrategy(100*time.Millisecond, 1*time.Minute, 2)
+}
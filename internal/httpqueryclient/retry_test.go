@@ -9,7 +9,6 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strconv"
-	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -79,10 +78,11 @@ func newTestClient(t *testing.T, addr string) *Client {
 	port, err := strconv.Atoi(portStr)
 	require.NoError(t, err)
 
-	return NewClient("http", host, port, ClientConfig{
+	return NewClient("http", []Endpoint{{Host: host, Port: port}}, ClientConfig{
 		TLSConfig:      nil,
 		Logger:         logging.NewDefaultLogger(logging.LogTrace, 0),
 		ConnectTimeout: 5 * time.Second,
+		RetryStrategy:  nil,
 	})
 }
 
@@ -280,9 +280,10 @@ func TestHandleRetries_FetchHandleStatus_RetriableThenSuccess(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	body, err := client.FetchHandleStatus(ctx, "/api/v1/request/result/abc", func(_ *http.Request) {}, 5)
+	body, endpoint, err := client.FetchHandleStatus(ctx, "/api/v1/request/result/abc", func(_ *http.Request) {}, 5)
 	require.NoError(t, err)
 	require.Contains(t, string(body), "success")
+	require.Equal(t, srv.Listener.Addr().String(), endpoint)
 
 	require.Equal(t, int32(3), atomic.LoadInt32(&attempt))
 }
@@ -303,7 +304,7 @@ func TestHandleRetries_FetchHandleStatus_404ReturnsNotFound(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	_, err := client.FetchHandleStatus(ctx, "/api/v1/request/result/abc", func(_ *http.Request) {}, 3)
+	_, _, err := client.FetchHandleStatus(ctx, "/api/v1/request/result/abc", func(_ *http.Request) {}, 3)
 	require.Error(t, err)
 	require.True(t, errors.Is(err, ErrQueryNotFound))
 
@@ -558,14 +559,14 @@ func TestHandleRequests_CorrectPaths(t *testing.T) {
 	authHandler := func(_ *http.Request) {}
 
 	t.Run("FetchHandleStatus", func(t *testing.T) {
-		_, err := client.FetchHandleStatus(ctx, "/api/v1/request/status/my-handle-id", authHandler, 0)
+		_, _, err := client.FetchHandleStatus(ctx, "/api/v1/request/status/my-handle-id", authHandler, 0)
 		require.NoError(t, err)
 		assert.Equal(t, "/api/v1/request/status/my-handle-id", lastPath)
 		assert.Equal(t, "GET", lastMethod)
 	})
 
 	t.Run("FetchHandleStatus_WithPrefix", func(t *testing.T) {
-		_, err := client.FetchHandleStatus(ctx, "/api/v1/request/status/abc", authHandler, 0)
+		_, _, err := client.FetchHandleStatus(ctx, "/api/v1/request/status/abc", authHandler, 0)
 		require.NoError(t, err)
 		assert.Equal(t, "/api/v1/request/status/abc", lastPath)
 	})
@@ -758,7 +759,7 @@ func TestHandleRetries_HTTP401NoRetry(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	_, err := client.FetchHandleStatus(ctx, "/api/v1/request/status/abc", func(_ *http.Request) {}, 5)
+	_, _, err := client.FetchHandleStatus(ctx, "/api/v1/request/status/abc", func(_ *http.Request) {}, 5)
 	require.Error(t, err)
 	require.True(t, errors.Is(err, ErrInvalidCredential))
 
@@ -838,7 +839,7 @@ func TestHandleRetries_FetchHandleStatus_StripsResultPrefix(t *testing.T) {
 	client := newTestClient(t, srv.Listener.Addr().String())
 	defer client.Close()
 
-	_, err := client.FetchHandleStatus(context.Background(), "/api/v1/request/status/my-id", func(_ *http.Request) {}, 0)
+	_, _, err := client.FetchHandleStatus(context.Background(), "/api/v1/request/status/my-id", func(_ *http.Request) {}, 0)
 	require.NoError(t, err)
 
 	assert.Equal(t, "/api/v1/request/status/my-id", receivedPath)
@@ -920,8 +921,125 @@ func TestRetries_HostHeaderSetCorrectly(t *testing.T) {
 	require.Len(t, hostHeaders, 2)
 
 	for _, h := range hostHeaders {
-		// The host header should contain the address we're connecting to
-		assert.True(t, strings.Contains(h, "127.0.0.1") || strings.Contains(h, "localhost"),
-			"unexpected host header: %s", h)
+		// The host header should contain the address we're connecting to, including the port.
+		assert.Equal(t, srv.Listener.Addr().String(), h)
 	}
 }
+
+// --- Retry strategy tests ---
+
+type recordingRetryStrategy struct {
+	requests []RetryRequest
+	maxRetry uint32
+}
+
+func (s *recordingRetryStrategy) RetryAfter(req *RetryRequest) (time.Duration, bool) {
+	s.requests = append(s.requests, *req)
+
+	return time.Millisecond, req.Attempt < s.maxRetry
+}
+
+func TestQueryRetries_StrategyDeclinesRetry(t *testing.T) {
+	var attempt int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&attempt, 1)
+		w.WriteHeader(200)
+		mustWrite(t, w, analyticsResponse(
+			withStatus("fatal"),
+			withErrors(retriableError(23001, "temporarily unavailable")),
+		))
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv.Listener.Addr().String())
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	strategy := &recordingRetryStrategy{maxRetry: 1}
+
+	_, err := client.Query(ctx, &QueryOptions{
+		Payload:       map[string]interface{}{"statement": "SELECT 1"},
+		AuthHandler:   func(_ *http.Request) {},
+		MaxRetries:    5,
+		RetryStrategy: strategy,
+	})
+	require.Error(t, err)
+
+	var qErr *QueryError
+
+	require.ErrorAs(t, err, &qErr)
+	require.Len(t, qErr.Errors, 1)
+	assert.Equal(t, uint32(23001), qErr.Errors[0].Code)
+
+	// 1 initial + 1 retry allowed by the strategy.
+	require.Equal(t, int32(2), atomic.LoadInt32(&attempt))
+	require.Len(t, strategy.requests, 2)
+
+	for i, req := range strategy.requests {
+		assert.Equal(t, uint32(i), req.Attempt)
+		assert.Equal(t, 200, req.HTTPStatusCode)
+		assert.NoError(t, req.TransportError)
+		require.Len(t, req.Errors, 1)
+		assert.Equal(t, uint32(23001), req.Errors[0].Code)
+		assert.False(t, req.Deadline.IsZero())
+	}
+}
+
+func TestQueryRetries_StrategyReceivesTransportError(t *testing.T) {
+	// Listen and immediately close so that we have an address that refuses connections.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	client := newTestClient(t, addr)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	strategy := &recordingRetryStrategy{maxRetry: 0}
+
+	_, err = client.Query(ctx, &QueryOptions{
+		Payload:       map[string]interface{}{"statement": "SELECT 1"},
+		AuthHandler:   func(_ *http.Request) {},
+		MaxRetries:    5,
+		RetryStrategy: strategy,
+	})
+	require.Error(t, err)
+
+	require.Len(t, strategy.requests, 1)
+	assert.Error(t, strategy.requests[0].TransportError)
+	assert.Zero(t, strategy.requests[0].HTTPStatusCode)
+}
+
+func TestQueryRetries_MaxRetriesCapsStrategy(t *testing.T) {
+	var attempt int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&attempt, 1)
+		w.WriteHeader(503)
+		mustWrite(t, w, []byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv.Listener.Addr().String())
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := client.Query(ctx, &QueryOptions{
+		Payload:       map[string]interface{}{"statement": "SELECT 1"},
+		AuthHandler:   func(_ *http.Request) {},
+		MaxRetries:    2,
+		RetryStrategy: &recordingRetryStrategy{maxRetry: 100},
+	})
+	require.ErrorIs(t, err, ErrServiceUnavailable)
+
+	require.Equal(t, int32(3), atomic.LoadInt32(&attempt))
+}
@@ -0,0 +1,86 @@
+package httpqueryclient
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+)
+
+// RequestStats collects statistics about the HTTP requests made on behalf of a single operation.
+// A nil RequestStats is valid, and records nothing.
+type RequestStats struct {
+	retries       atomic.Uint32
+	bytesReceived atomic.Uint64
+}
+
+// Retries returns the number of times the operation was retried.
+func (s *RequestStats) Retries() uint32 {
+	if s == nil {
+		return 0
+	}
+
+	return s.retries.Load()
+}
+
+// BytesReceived returns the number of response body bytes read so far, across all attempts.
+func (s *RequestStats) BytesReceived() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	return s.bytesReceived.Load()
+}
+
+func (s *RequestStats) addRetry() {
+	if s == nil {
+		return
+	}
+
+	s.retries.Add(1)
+}
+
+func (s *RequestStats) addBytesReceived(n int) {
+	if s == nil || n <= 0 {
+		return
+	}
+
+	s.bytesReceived.Add(uint64(n))
+}
+
+type requestStatsContextKey struct{}
+
+// ContextWithRequestStats returns a context which causes requests sent with it to record their statistics
+// into stats.
+func ContextWithRequestStats(ctx context.Context, stats *RequestStats) context.Context {
+	return context.WithValue(ctx, requestStatsContextKey{}, stats)
+}
+
+func requestStatsFromContext(ctx context.Context) *RequestStats {
+	stats, _ := ctx.Value(requestStatsContextKey{}).(*RequestStats)
+
+	return stats
+}
+
+// countingReadCloser records the number of bytes read from a response body.
+type countingReadCloser struct {
+	io.ReadCloser
+	stats *RequestStats
+}
+
+func newCountingReadCloser(body io.ReadCloser, stats *RequestStats) io.ReadCloser {
+	if stats == nil {
+		return body
+	}
+
+	return &countingReadCloser{
+		ReadCloser: body,
+		stats:      stats,
+	}
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.stats.addBytesReceived(n)
+
+	return n, err //nolint:wrapcheck
+}
@@ -0,0 +1,80 @@
+package httpqueryclient
+
+import (
+	"context"
+)
+
+// Attribute keys used on spans created by the client, and by users of the client.
+const (
+	SpanAttributeSystem          = "db.system"
+	SpanAttributeService         = "db.couchbase.service"
+	SpanAttributeOperation       = "db.operation"
+	SpanAttributeStatement       = "db.statement"
+	SpanAttributeRetries         = "db.couchbase.retries"
+	SpanAttributeServerAddress   = "server.address"
+	SpanAttributeServerPort      = "server.port"
+	SpanAttributeStatusCode      = "http.response.status_code"
+	SpanAttributeClientContextID = "db.couchbase.client_context_id"
+	SpanAttributeRequestID       = "db.couchbase.request_id"
+)
+
+// SpanNameAttempt is the name given to the span created for each HTTP attempt.
+const SpanNameAttempt = "dispatch_to_server"
+
+// Span records the details of a single unit of work.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	End()
+}
+
+// ParentSpan is a span which the client can create child spans of, one for each HTTP attempt.
+type ParentSpan interface {
+	StartChild(name string) Span
+}
+
+type parentSpanContextKey struct{}
+
+// ContextWithParentSpan returns a context which causes requests sent with it to create child spans of parent.
+func ContextWithParentSpan(ctx context.Context, parent ParentSpan) context.Context {
+	return context.WithValue(ctx, parentSpanContextKey{}, parent)
+}
+
+func parentSpanFromContext(ctx context.Context) ParentSpan {
+	parent, _ := ctx.Value(parentSpanContextKey{}).(ParentSpan)
+
+	return parent
+}
+
+type noopSpan struct{}
+
+func (s noopSpan) SetAttribute(_ string, _ interface{}) {}
+
+func (s noopSpan) End() {}
+
+func startAttemptSpan(ctx context.Context, state *retryState, clientContextID string) Span {
+	parent := parentSpanFromContext(ctx)
+	if parent == nil {
+		return noopSpan{}
+	}
+
+	span := parent.StartChild(SpanNameAttempt)
+	span.SetAttribute(SpanAttributeRetries, state.retries)
+	span.SetAttribute(SpanAttributeServerAddress, state.endpoint.Host)
+	span.SetAttribute(SpanAttributeServerPort, state.endpoint.Port)
+
+	if clientContextID != "" {
+		span.SetAttribute(SpanAttributeClientContextID, clientContextID)
+	}
+
+	return span
+}
+
+// setSpanRequestID records the request ID from the response on the span, if the server sent it before the rows.
+func setSpanRequestID(span Span, streamer *queryStreamer) {
+	requestID := earlyRequestID(streamer)
+	if requestID == "" {
+		return
+	}
+
+	span.SetAttribute(SpanAttributeRequestID, requestID)
+}
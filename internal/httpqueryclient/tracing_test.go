@@ -0,0 +1,127 @@
+package httpqueryclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedSpan struct {
+	name       string
+	attributes map[string]interface{}
+	ended      bool
+}
+
+func (s *recordedSpan) SetAttribute(key string, value interface{}) {
+	s.attributes[key] = value
+}
+
+func (s *recordedSpan) End() {
+	s.ended = true
+}
+
+type recordingParentSpan struct {
+	lock     sync.Mutex
+	children []*recordedSpan
+}
+
+func (p *recordingParentSpan) StartChild(name string) Span {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	span := &recordedSpan{
+		name:       name,
+		attributes: make(map[string]interface{}),
+		ended:      false,
+	}
+	p.children = append(p.children, span)
+
+	return span
+}
+
+func TestQuery_AttemptSpanPerAttempt(t *testing.T) {
+	var attempt int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if atomic.AddInt32(&attempt, 1) == 1 {
+			w.WriteHeader(503)
+			mustWrite(t, w, analyticsResponse(
+				withStatus("fatal"),
+				withErrors(retriableError(23001, "temporarily unavailable")),
+			))
+
+			return
+		}
+
+		w.WriteHeader(200)
+		mustWrite(t, w, analyticsResponse(
+			withResults(1),
+			func(m map[string]interface{}) {
+				m["requestID"] = "req-1"
+			},
+		))
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv.Listener.Addr().String())
+	defer client.Close()
+
+	parent := &recordingParentSpan{}
+
+	ctx, cancel := context.WithTimeout(ContextWithParentSpan(context.Background(), parent), 30*time.Second)
+	defer cancel()
+
+	result, err := client.Query(ctx, &QueryOptions{
+		Payload:     map[string]interface{}{"statement": "SELECT 1", "client_context_id": "ctx-1"},
+		AuthHandler: func(_ *http.Request) {},
+		MaxRetries:  5,
+	})
+	require.NoError(t, err)
+
+	for result.NextRow() != nil {
+	}
+
+	require.NoError(t, result.Err())
+
+	require.Len(t, parent.children, 2)
+
+	for i, span := range parent.children {
+		assert.Equal(t, SpanNameAttempt, span.name)
+		assert.True(t, span.ended)
+		assert.Equal(t, uint32(i), span.attributes[SpanAttributeRetries])
+		assert.Equal(t, client.Endpoints()[0].Host, span.attributes[SpanAttributeServerAddress])
+		assert.Equal(t, client.Endpoints()[0].Port, span.attributes[SpanAttributeServerPort])
+		assert.Equal(t, "ctx-1", span.attributes[SpanAttributeClientContextID])
+	}
+
+	assert.Equal(t, 503, parent.children[0].attributes[SpanAttributeStatusCode])
+	assert.Equal(t, 200, parent.children[1].attributes[SpanAttributeStatusCode])
+	assert.Equal(t, "req-1", parent.children[1].attributes[SpanAttributeRequestID])
+}
+
+func TestQuery_NoParentSpan(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(200)
+		mustWrite(t, w, analyticsResponse(withResults(1)))
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv.Listener.Addr().String())
+	defer client.Close()
+
+	result, err := client.Query(context.Background(), &QueryOptions{
+		Payload:     map[string]interface{}{"statement": "SELECT 1"},
+		AuthHandler: func(_ *http.Request) {},
+		MaxRetries:  0,
+	})
+	require.NoError(t, err)
+
+	assert.NotNil(t, result.NextRow())
+}
@@ -0,0 +1,362 @@
+// Package recordreplay implements HTTP transports which record responses to files on disk, and replay
+// them later without contacting a server.
+package recordreplay
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// Mode specifies whether the transport records or replays responses.
+type Mode uint
+
+const (
+	// ModeRecord sends requests to the server and records the responses.
+	ModeRecord Mode = iota + 1
+
+	// ModeReplay serves previously recorded responses without contacting the server.
+	ModeReplay
+)
+
+// volatileFields are fields of JSON request payloads which vary between otherwise identical requests, and are
+// therefore excluded when matching requests to recordings.
+var volatileFields = []string{"client_context_id", "timeout"}
+
+// Recording is the format of the files written by the transport.
+type Recording struct {
+	Request RecordedRequest `json:"request"`
+
+	// Responses are the responses received for the request, in the order that they were read, such as a 503
+	// followed by the 200 of the retry. They are replayed in the same order, with the last one repeated once
+	// they have all been served.
+	Responses []RecordedResponse `json:"responses"`
+}
+
+// RecordedRequest describes the request that a recording was made for.
+type RecordedRequest struct {
+	Method  string          `json:"method"`
+	Path    string          `json:"path"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Body    string          `json:"body,omitempty"`
+}
+
+// RecordedResponse is a recorded response.
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+
+	// Truncated is set if the response was closed before its body had been read in full, in which case Body
+	// holds only what was read.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Transport records or replays responses. A single Transport is shared by the transports of every endpoint, each
+// wrapped using Wrap, so that the responses for a request are kept in order in one recording even when it fails
+// over between endpoints.
+type Transport struct {
+	mode Mode
+	dir  string
+
+	lock sync.Mutex
+	// recorded holds the responses recorded for each key by this transport, so that recordings made by an
+	// earlier run are replaced rather than added to.
+	recorded map[string][]RecordedResponse
+	// replayed holds the number of responses replayed for each key.
+	replayed map[string]int
+}
+
+// NewTransport creates a new Transport which stores recordings in dir.
+func NewTransport(mode Mode, dir string) *Transport {
+	return &Transport{
+		mode:     mode,
+		dir:      dir,
+		lock:     sync.Mutex{},
+		recorded: make(map[string][]RecordedResponse),
+		replayed: make(map[string]int),
+	}
+}
+
+// Wrap returns an http.RoundTripper which records or replays responses through t. In record mode requests are
+// sent using next, in replay mode next is not used.
+func (t *Transport) Wrap(next http.RoundTripper) http.RoundTripper {
+	return &endpointTransport{
+		transport: t,
+		next:      next,
+	}
+}
+
+// endpointTransport is the http.RoundTripper returned by Wrap.
+type endpointTransport struct {
+	transport *Transport
+	next      http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (e *endpointTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+
+	if req.Body != nil {
+		var err error
+
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+
+		err = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to close request body: %w", err)
+		}
+	}
+
+	recorded, key := newRecordedRequest(req, body)
+
+	if e.transport.mode == ModeReplay {
+		return e.transport.replay(req, recorded, key)
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+
+	resp, err := e.next.RoundTrip(req)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	resp.Body = &recordingReadCloser{
+		parent:    resp.Body,
+		transport: e.transport,
+		key:       key,
+		request:   recorded,
+		resp:      resp,
+		buf:       bytes.Buffer{},
+		done:      false,
+		truncated: false,
+	}
+
+	return resp, nil
+}
+
+// CloseIdleConnections closes any idle connections held by the underlying transport.
+func (e *endpointTransport) CloseIdleConnections() {
+	if closer, ok := e.next.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+func (t *Transport) replay(req *http.Request, recorded RecordedRequest, key string) (*http.Response, error) {
+	data, err := os.ReadFile(t.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return newResponse(req, http.StatusNotFound, http.Header{"Content-Type": {"application/json"}},
+			noRecordingBody(recorded)), nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+
+	var recording Recording
+
+	err = json.Unmarshal(data, &recording)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse recording %s: %w", t.path(key), err)
+	}
+
+	if len(recording.Responses) == 0 {
+		return nil, fmt.Errorf("recording %s has no responses", t.path(key)) //nolint:err113
+	}
+
+	t.lock.Lock()
+	idx := t.replayed[key]
+	t.replayed[key]++
+	t.lock.Unlock()
+
+	if idx >= len(recording.Responses) {
+		idx = len(recording.Responses) - 1
+	}
+
+	response := recording.Responses[idx]
+
+	return newResponse(req, response.StatusCode, response.Header, response.Body), nil
+}
+
+// write adds response to the recording for key, after any responses already recorded for it by this transport.
+func (t *Transport) write(key string, request RecordedRequest, response RecordedResponse) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.recorded[key] = append(t.recorded[key], response)
+
+	data, err := json.MarshalIndent(&Recording{
+		Request:   request,
+		Responses: t.recorded[key],
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode recording: %w", err)
+	}
+
+	err = os.MkdirAll(t.dir, 0o750)
+	if err != nil {
+		return fmt.Errorf("failed to create recording directory: %w", err)
+	}
+
+	err = os.WriteFile(t.path(key), append(data, '\n'), 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+
+	return nil
+}
+
+func (t *Transport) path(key string) string {
+	return filepath.Join(t.dir, key+".json")
+}
+
+// newRecordedRequest creates the description of the request, and the key used to identify recordings of it.
+// JSON payloads are normalized by removing volatile fields and sorting object keys, so that requests which only
+// differ by these fields share a recording.
+func newRecordedRequest(req *http.Request, body []byte) (RecordedRequest, string) {
+	recorded := RecordedRequest{
+		Method:  req.Method,
+		Path:    req.URL.Path,
+		Payload: nil,
+		Body:    "",
+	}
+
+	var payload map[string]interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	if len(body) > 0 && decoder.Decode(&payload) == nil {
+		for _, field := range volatileFields {
+			delete(payload, field)
+		}
+
+		// Encoding a map always sorts the keys, which makes the encoding stable.
+		normalized, err := json.Marshal(payload)
+		if err == nil {
+			recorded.Payload = normalized
+		}
+	}
+
+	if recorded.Payload == nil {
+		recorded.Body = string(body)
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(recorded.Method + " " + recorded.Path + "\n"))
+	hash.Write(recorded.Payload)
+	hash.Write([]byte(recorded.Body))
+
+	return recorded, hex.EncodeToString(hash.Sum(nil))
+}
+
+func newResponse(req *http.Request, statusCode int, header http.Header, body string) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return &http.Response{ //nolint:exhaustruct
+		Status:        strconv.Itoa(statusCode) + " " + http.StatusText(statusCode),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader([]byte(body))),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+func noRecordingBody(recorded RecordedRequest) string {
+	msg := fmt.Sprintf("no recording found for %s %s", recorded.Method, recorded.Path)
+	if recorded.Payload != nil {
+		msg += " with payload " + string(recorded.Payload)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"errors": []map[string]interface{}{
+			{"code": 0, "msg": msg},
+		},
+	})
+
+	return string(body)
+}
+
+// recordingReadCloser passes through a response body, writing a recording once the body has been read in full
+// or closed. Responses which fail to be read are not recorded.
+type recordingReadCloser struct {
+	parent    io.ReadCloser
+	transport *Transport
+	key       string
+	request   RecordedRequest
+	resp      *http.Response
+	buf       bytes.Buffer
+	done      bool
+	truncated bool
+}
+
+func (r *recordingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.parent.Read(p)
+	r.buf.Write(p[:n])
+
+	if err != nil && !r.done {
+		r.done = true
+
+		if errors.Is(err, io.EOF) {
+			recordErr := r.record()
+			if recordErr != nil {
+				return n, recordErr
+			}
+		}
+	}
+
+	return n, err //nolint:wrapcheck
+}
+
+func (r *recordingReadCloser) Close() error {
+	// Only what was read is recorded, as reading the rest could mean streaming a large result which the reader
+	// has abandoned. Readers often stop once they have decoded a complete response, without waiting for EOF, so
+	// the recording is usually complete despite being marked as truncated.
+	if !r.done {
+		r.done = true
+		r.truncated = true
+
+		recordErr := r.record()
+		if recordErr != nil {
+			_ = r.parent.Close()
+
+			return recordErr
+		}
+	}
+
+	return r.parent.Close() //nolint:wrapcheck
+}
+
+func (r *recordingReadCloser) record() error {
+	header := http.Header{}
+	if contentType := r.resp.Header.Get("Content-Type"); contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	return r.transport.write(r.key, r.request, RecordedResponse{
+		StatusCode: r.resp.StatusCode,
+		Header:     header,
+		Body:       r.buf.String(),
+		Truncated:  r.truncated,
+	})
+}
@@ -0,0 +1,273 @@
+package recordreplay
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEchoServer(t *testing.T) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var requests int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		body, _ := io.ReadAll(r.Body)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"results":[` + string(body) + `]}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &requests
+}
+
+func doRequest(t *testing.T, transport http.RoundTripper, url, body string) (int, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, url+"/api/v1/request", strings.NewReader(body))
+	require.NoError(t, err)
+
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+
+	respBody, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	return resp.StatusCode, string(respBody)
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	srv, requests := newEchoServer(t)
+	dir := t.TempDir()
+
+	recorder := NewTransport(ModeRecord, dir).Wrap(http.DefaultTransport)
+
+	status, body := doRequest(t, recorder, srv.URL,
+		`{"statement":"SELECT 1","client_context_id":"a","timeout":"10s","args":[1]}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"results":[{"statement":"SELECT 1","client_context_id":"a","timeout":"10s","args":[1]}]}`, body)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	srv.Close()
+
+	replayer := NewTransport(ModeReplay, dir).Wrap(nil)
+
+	// Volatile fields and key order do not affect matching.
+	status, replayed := doRequest(t, replayer, "http://127.0.0.1:1",
+		`{"args":[1],"timeout":"20s","statement":"SELECT 1","client_context_id":"b"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, body, replayed)
+	assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+}
+
+func TestReplayMissingRecording(t *testing.T) {
+	replayer := NewTransport(ModeReplay, t.TempDir()).Wrap(nil)
+
+	status, body := doRequest(t, replayer, "http://127.0.0.1:1", `{"statement":"SELECT 2"}`)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Contains(t, body, "no recording found for POST /api/v1/request")
+	assert.Contains(t, body, "SELECT 2")
+}
+
+func TestRecordDifferentPayloads(t *testing.T) {
+	srv, _ := newEchoServer(t)
+	dir := t.TempDir()
+
+	recorder := NewTransport(ModeRecord, dir).Wrap(http.DefaultTransport)
+
+	doRequest(t, recorder, srv.URL, `{"statement":"SELECT $1","args":[1]}`)
+	doRequest(t, recorder, srv.URL, `{"statement":"SELECT $1","args":[2]}`)
+	doRequest(t, recorder, srv.URL, `{"statement":"SELECT $1","args":[1],"query_context":"default:a.b"}`)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+}
+
+func TestRecordIncompleteResponseNotRecorded(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"results":[1,`))
+		w.(http.Flusher).Flush() //nolint:forcetypeassert
+
+		conn, _, err := w.(http.Hijacker).Hijack() //nolint:forcetypeassert
+		if err == nil {
+			_ = conn.Close()
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	recorder := NewTransport(ModeRecord, dir).Wrap(http.DefaultTransport)
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/request", strings.NewReader(`{}`))
+	require.NoError(t, err)
+
+	resp, err := recorder.RoundTrip(req)
+	require.NoError(t, err)
+
+	_, err = io.ReadAll(resp.Body)
+	require.Error(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestRecordThenReplayInOrder(t *testing.T) {
+	var requests int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"errors":[{"code":23000,"msg":"unavailable","retriable":true}]}`))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"results":[1]}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	recorder := NewTransport(ModeRecord, dir).Wrap(http.DefaultTransport)
+
+	status, _ := doRequest(t, recorder, srv.URL, `{"statement":"SELECT 1"}`)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+
+	status, _ = doRequest(t, recorder, srv.URL, `{"statement":"SELECT 1"}`)
+	assert.Equal(t, http.StatusOK, status)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	replayer := NewTransport(ModeReplay, dir).Wrap(nil)
+
+	status, _ = doRequest(t, replayer, "http://127.0.0.1:1", `{"statement":"SELECT 1"}`)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+
+	// The last response is repeated once they have all been replayed.
+	for range 2 {
+		status, body := doRequest(t, replayer, "http://127.0.0.1:1", `{"statement":"SELECT 1"}`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, `{"results":[1]}`, body)
+	}
+
+	// Recording again replaces the responses recorded by an earlier run, rather than adding to them.
+	rerecorder := NewTransport(ModeRecord, dir).Wrap(http.DefaultTransport)
+
+	status, _ = doRequest(t, rerecorder, srv.URL, `{"statement":"SELECT 1"}`)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _ = doRequest(t, NewTransport(ModeReplay, dir).Wrap(nil), "http://127.0.0.1:1", `{"statement":"SELECT 1"}`)
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestRecordClosedEarlyIsTruncated(t *testing.T) {
+	done := make(chan struct{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"results":[1,`))
+		w.(http.Flusher).Flush() //nolint:forcetypeassert
+
+		// The rest of the result is never sent, so the body can only be closed early.
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	t.Cleanup(func() {
+		close(done)
+		srv.Close()
+	})
+
+	dir := t.TempDir()
+	recorder := NewTransport(ModeRecord, dir).Wrap(http.DefaultTransport)
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/request", strings.NewReader(`{}`))
+	require.NoError(t, err)
+
+	resp, err := recorder.RoundTrip(req)
+	require.NoError(t, err)
+
+	buf := make([]byte, len(`{"results":[1,`))
+	_, err = io.ReadFull(resp.Body, buf)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	data, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	require.NoError(t, err)
+
+	var recording Recording
+	require.NoError(t, json.Unmarshal(data, &recording))
+	require.Len(t, recording.Responses, 1)
+	assert.True(t, recording.Responses[0].Truncated)
+	assert.Equal(t, `{"results":[1,`, recording.Responses[0].Body)
+}
+
+func TestRecordThenReplayAcrossEndpoints(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"errors":[{"code":23000,"msg":"unavailable","retriable":true}]}`))
+	}))
+	t.Cleanup(down.Close)
+
+	up, _ := newEchoServer(t)
+	dir := t.TempDir()
+
+	// Each endpoint has its own transport, but they share the recorder.
+	recorder := NewTransport(ModeRecord, dir)
+
+	status, _ := doRequest(t, recorder.Wrap(http.DefaultTransport), down.URL, `{"statement":"SELECT 1"}`)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+
+	status, _ = doRequest(t, recorder.Wrap(http.DefaultTransport), up.URL, `{"statement":"SELECT 1"}`)
+	assert.Equal(t, http.StatusOK, status)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	data, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	require.NoError(t, err)
+
+	var recording Recording
+	require.NoError(t, json.Unmarshal(data, &recording))
+	require.Len(t, recording.Responses, 2)
+	assert.Equal(t, http.StatusServiceUnavailable, recording.Responses[0].StatusCode)
+	assert.Equal(t, http.StatusOK, recording.Responses[1].StatusCode)
+
+	// Failing over to another endpoint continues from the next response, rather than starting again.
+	replayer := NewTransport(ModeReplay, dir)
+
+	status, _ = doRequest(t, replayer.Wrap(nil), "http://127.0.0.1:1", `{"statement":"SELECT 1"}`)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+
+	status, _ = doRequest(t, replayer.Wrap(nil), "http://127.0.0.1:2", `{"statement":"SELECT 1"}`)
+	assert.Equal(t, http.StatusOK, status)
+}
@@ -0,0 +1,186 @@
+package cbanalytics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/couchbase/gocbanalytics/internal/httpqueryclient"
+)
+
+// Names of the metrics recorded by the SDK for each operation.
+const (
+	// MetricOperationDuration is the duration of an operation in microseconds. For operations which stream
+	// results, this is the time until the final row has been read.
+	MetricOperationDuration = "db.couchbase.operations.duration"
+
+	// MetricOperationRetries is the number of times an operation was retried.
+	MetricOperationRetries = "db.couchbase.operations.retries"
+
+	// MetricOperationBytesReceived is the number of response bytes received for an operation, across all attempts.
+	MetricOperationBytesReceived = "db.couchbase.operations.bytes_received"
+
+	// MetricOperationRows is the number of rows streamed by an operation. This is only recorded for operations
+	// which stream results.
+	MetricOperationRows = "db.couchbase.operations.rows"
+)
+
+// Tag keys set on the metrics recorded by the SDK.
+const (
+	MetricTagService   = "db.couchbase.service"
+	MetricTagOperation = "db.operation"
+
+	// MetricTagOutcome is the class of error that the operation failed with, or "Success".
+	// This is only set on MetricOperationDuration.
+	MetricTagOutcome = "outcome"
+)
+
+// Values of MetricTagOutcome.
+const (
+	MetricOutcomeSuccess            = "Success"
+	MetricOutcomeTimeout            = "Timeout"
+	MetricOutcomeCanceled           = "Canceled"
+	MetricOutcomeInvalidCredential  = "InvalidCredential"
+	MetricOutcomeInvalidArgument    = "InvalidArgument"
+	MetricOutcomeServiceUnavailable = "ServiceUnavailable"
+	MetricOutcomeQueryNotFound      = "QueryNotFound"
+	MetricOutcomeQueueFull          = "QueueFull"
+	MetricOutcomeCircuitOpen        = "CircuitOpen"
+	MetricOutcomeQueryError         = "QueryError"
+	MetricOutcomeAnalyticsError     = "AnalyticsError"
+	MetricOutcomeOther              = "Other"
+)
+
+// Meter describes the metrics abstraction in the SDK.
+// VOLATILE: This API is subject to change at any time.
+type Meter interface {
+	// ValueRecorder returns the ValueRecorder for the given metric name and tags.
+	ValueRecorder(name string, tags map[string]string) (ValueRecorder, error)
+}
+
+// ValueRecorder is used for recording the values of a metric.
+// VOLATILE: This API is subject to change at any time.
+type ValueRecorder interface {
+	// RecordValue records a single value.
+	RecordValue(val uint64)
+}
+
+// NoopMeter is a Meter which does nothing.
+// This is equivalent to specifying Meter on ClusterOptions as nil.
+type NoopMeter struct{}
+
+// NewNoopMeter creates a new NoopMeter instance.
+func NewNoopMeter() *NoopMeter {
+	return &NoopMeter{}
+}
+
+// ValueRecorder returns a ValueRecorder which does nothing.
+func (m *NoopMeter) ValueRecorder(_ string, _ map[string]string) (ValueRecorder, error) {
+	return noopValueRecorder{}, nil
+}
+
+type noopValueRecorder struct{}
+
+func (r noopValueRecorder) RecordValue(_ uint64) {}
+
+// operationMetrics records the metrics for a single operation.
+type operationMetrics struct {
+	meter     Meter
+	logger    Logger
+	operation string
+	start     time.Time
+	stats     *httpqueryclient.RequestStats
+}
+
+// startOperationMetrics begins recording metrics for an operation, and returns a context which causes the
+// http client to record request statistics for it.
+func startOperationMetrics(ctx context.Context, meter Meter, logger Logger, operation string) (context.Context,
+	*operationMetrics) {
+	if meter == nil {
+		return ctx, nil
+	}
+
+	stats := &httpqueryclient.RequestStats{}
+
+	return httpqueryclient.ContextWithRequestStats(ctx, stats), &operationMetrics{
+		meter:     meter,
+		logger:    logger,
+		operation: operation,
+		start:     time.Now(),
+		stats:     stats,
+	}
+}
+
+// finish records the metrics for an operation which does not stream results.
+func (m *operationMetrics) finish(err error) {
+	if m == nil {
+		return
+	}
+
+	m.record(MetricOperationDuration, map[string]string{
+		MetricTagService:   "analytics",
+		MetricTagOperation: m.operation,
+		MetricTagOutcome:   metricOutcome(err),
+	}, uint64(time.Since(m.start).Microseconds()))
+
+	m.record(MetricOperationRetries, m.tags(), uint64(m.stats.Retries()))
+	m.record(MetricOperationBytesReceived, m.tags(), m.stats.BytesReceived())
+}
+
+// finishWithRows records the metrics for an operation which streams results.
+func (m *operationMetrics) finishWithRows(err error, rows uint64) {
+	if m == nil {
+		return
+	}
+
+	m.finish(err)
+	m.record(MetricOperationRows, m.tags(), rows)
+}
+
+func (m *operationMetrics) tags() map[string]string {
+	return map[string]string{
+		MetricTagService:   "analytics",
+		MetricTagOperation: m.operation,
+	}
+}
+
+func (m *operationMetrics) record(name string, tags map[string]string, val uint64) {
+	recorder, err := m.meter.ValueRecorder(name, tags)
+	if err != nil {
+		m.logger.Debug("Failed to create value recorder for %s: %v", name, err)
+
+		return
+	}
+
+	recorder.RecordValue(val)
+}
+
+// metricOutcome classifies an error for the MetricTagOutcome tag.
+func metricOutcome(err error) string {
+	switch {
+	case err == nil:
+		return MetricOutcomeSuccess
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return MetricOutcomeTimeout
+	case errors.Is(err, context.Canceled):
+		return MetricOutcomeCanceled
+	case errors.Is(err, ErrInvalidCredential):
+		return MetricOutcomeInvalidCredential
+	case errors.Is(err, ErrInvalidArgument):
+		return MetricOutcomeInvalidArgument
+	case errors.Is(err, ErrServiceUnavailable):
+		return MetricOutcomeServiceUnavailable
+	case errors.Is(err, ErrQueryNotFound):
+		return MetricOutcomeQueryNotFound
+	case errors.Is(err, ErrQueueFull):
+		return MetricOutcomeQueueFull
+	case errors.Is(err, ErrCircuitOpen):
+		return MetricOutcomeCircuitOpen
+	case errors.Is(err, ErrQuery):
+		return MetricOutcomeQueryError
+	case errors.Is(err, ErrAnalytics):
+		return MetricOutcomeAnalyticsError
+	default:
+		return MetricOutcomeOther
+	}
+}
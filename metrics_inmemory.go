@@ -0,0 +1,321 @@
+package cbanalytics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Default histogram bucket upper bounds used by InMemoryMeter for the metrics recorded by the SDK.
+var (
+	// defaultDurationBuckets are in microseconds, ranging from 1 millisecond to 2 minutes.
+	defaultDurationBuckets = []uint64{
+		1000, 2500, 5000, 10000, 25000, 50000, 100000, 250000, 500000,
+		1000000, 2500000, 5000000, 10000000, 30000000, 60000000, 120000000,
+	}
+
+	defaultRetryBuckets = []uint64{0, 1, 2, 3, 5, 10, 20}
+
+	// defaultBytesBuckets range from 1KiB to 256MiB.
+	defaultBytesBuckets = []uint64{
+		1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20, 64 << 20, 256 << 20,
+	}
+
+	defaultRowBuckets = []uint64{0, 1, 10, 100, 1000, 10000, 100000, 1000000}
+)
+
+// InMemoryMeterOptions is the set of options available when creating an InMemoryMeter.
+type InMemoryMeterOptions struct {
+	// Buckets specifies the histogram bucket upper bounds to use for each metric name.
+	// Metrics which do not have an entry use the default buckets for that metric. Durations default to
+	// buckets from 1 millisecond to 2 minutes, and metrics not recorded by the SDK use the same buckets.
+	Buckets map[string][]uint64
+}
+
+// NewInMemoryMeterOptions creates a new instance of InMemoryMeterOptions.
+func NewInMemoryMeterOptions() *InMemoryMeterOptions {
+	return &InMemoryMeterOptions{
+		Buckets: nil,
+	}
+}
+
+// SetBuckets sets the histogram bucket upper bounds to use for the given metric name.
+func (opts *InMemoryMeterOptions) SetBuckets(name string, buckets []uint64) *InMemoryMeterOptions {
+	if opts.Buckets == nil {
+		opts.Buckets = make(map[string][]uint64)
+	}
+
+	opts.Buckets[name] = buckets
+
+	return opts
+}
+
+// InMemoryMeter is a Meter which aggregates values into bucketed histograms held in memory.
+// The histograms can be read using Snapshot, or exposed in the Prometheus text format using WritePrometheus,
+// or by registering the meter as an http.Handler.
+// VOLATILE: This API is subject to change at any time.
+type InMemoryMeter struct {
+	buckets map[string][]uint64
+
+	lock       sync.RWMutex
+	histograms map[string]*inMemoryHistogram
+}
+
+// NewInMemoryMeter creates a new InMemoryMeter.
+func NewInMemoryMeter(opts ...*InMemoryMeterOptions) *InMemoryMeter {
+	buckets := map[string][]uint64{
+		MetricOperationDuration:      defaultDurationBuckets,
+		MetricOperationRetries:       defaultRetryBuckets,
+		MetricOperationBytesReceived: defaultBytesBuckets,
+		MetricOperationRows:          defaultRowBuckets,
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+
+		for name, bounds := range opt.Buckets {
+			buckets[name] = bounds
+		}
+	}
+
+	for name, bounds := range buckets {
+		sorted := make([]uint64, len(bounds))
+		copy(sorted, bounds)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+		buckets[name] = sorted
+	}
+
+	return &InMemoryMeter{
+		buckets:    buckets,
+		lock:       sync.RWMutex{},
+		histograms: make(map[string]*inMemoryHistogram),
+	}
+}
+
+// ValueRecorder returns the histogram for the given metric name and tags, creating it if it does not exist.
+func (m *InMemoryMeter) ValueRecorder(name string, tags map[string]string) (ValueRecorder, error) {
+	key := histogramKey(name, tags)
+
+	m.lock.RLock()
+	histogram, ok := m.histograms[key]
+	m.lock.RUnlock()
+
+	if ok {
+		return histogram, nil
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	histogram, ok = m.histograms[key]
+	if ok {
+		return histogram, nil
+	}
+
+	bounds, ok := m.buckets[name]
+	if !ok {
+		bounds = defaultDurationBuckets
+	}
+
+	tagsCopy := make(map[string]string, len(tags))
+	for k, v := range tags {
+		tagsCopy[k] = v
+	}
+
+	histogram = &inMemoryHistogram{
+		name:   name,
+		tags:   tagsCopy,
+		bounds: bounds,
+		counts: make([]atomic.Uint64, len(bounds)+1),
+		sum:    atomic.Uint64{},
+	}
+	m.histograms[key] = histogram
+
+	return histogram, nil
+}
+
+// HistogramBucket is a single bucket of a HistogramSnapshot.
+type HistogramBucket struct {
+	// UpperBound is the inclusive upper bound of the bucket.
+	UpperBound uint64
+
+	// Count is the cumulative number of values less than or equal to UpperBound.
+	Count uint64
+}
+
+// HistogramSnapshot is a point in time copy of a histogram held by an InMemoryMeter.
+type HistogramSnapshot struct {
+	Name    string
+	Tags    map[string]string
+	Buckets []HistogramBucket
+	Count   uint64
+	Sum     uint64
+}
+
+// Snapshot returns a copy of every histogram held by the meter, ordered by name and then tags.
+func (m *InMemoryMeter) Snapshot() []HistogramSnapshot {
+	m.lock.RLock()
+	keys := make([]string, 0, len(m.histograms))
+
+	for key := range m.histograms {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	snapshots := make([]HistogramSnapshot, len(keys))
+	for i, key := range keys {
+		snapshots[i] = m.histograms[key].snapshot()
+	}
+	m.lock.RUnlock()
+
+	return snapshots
+}
+
+// WritePrometheus writes every histogram held by the meter to w, in the Prometheus text exposition format.
+// Any characters in metric names and tag keys which are not valid in Prometheus are replaced with underscores.
+func (m *InMemoryMeter) WritePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	lastName := ""
+
+	for _, snapshot := range m.Snapshot() {
+		name := prometheusName(snapshot.Name)
+		if name != lastName {
+			fmt.Fprintf(bw, "# TYPE %s histogram\n", name)
+			lastName = name
+		}
+
+		labels := prometheusLabels(snapshot.Tags)
+
+		for _, bucket := range snapshot.Buckets {
+			fmt.Fprintf(bw, "%s_bucket{%s} %d\n", name,
+				appendPrometheusLabel(labels, "le", strconv.FormatUint(bucket.UpperBound, 10)), bucket.Count)
+		}
+
+		fmt.Fprintf(bw, "%s_bucket{%s} %d\n", name, appendPrometheusLabel(labels, "le", "+Inf"), snapshot.Count)
+		fmt.Fprintf(bw, "%s_sum{%s} %d\n", name, labels, snapshot.Sum)
+		fmt.Fprintf(bw, "%s_count{%s} %d\n", name, labels, snapshot.Count)
+	}
+
+	return bw.Flush() //nolint:wrapcheck
+}
+
+// ServeHTTP writes every histogram held by the meter in the Prometheus text exposition format.
+func (m *InMemoryMeter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	_ = m.WritePrometheus(w)
+}
+
+type inMemoryHistogram struct {
+	name   string
+	tags   map[string]string
+	bounds []uint64
+
+	// counts holds the non-cumulative count of each bucket, with a final bucket for values above every bound.
+	counts []atomic.Uint64
+	sum    atomic.Uint64
+}
+
+func (h *inMemoryHistogram) RecordValue(val uint64) {
+	idx := sort.Search(len(h.bounds), func(i int) bool { return val <= h.bounds[i] })
+
+	h.counts[idx].Add(1)
+	h.sum.Add(val)
+}
+
+func (h *inMemoryHistogram) snapshot() HistogramSnapshot {
+	buckets := make([]HistogramBucket, len(h.bounds))
+
+	var cumulative uint64
+
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i].Load()
+		buckets[i] = HistogramBucket{
+			UpperBound: bound,
+			Count:      cumulative,
+		}
+	}
+
+	tags := make(map[string]string, len(h.tags))
+	for k, v := range h.tags {
+		tags[k] = v
+	}
+
+	return HistogramSnapshot{
+		Name:    h.name,
+		Tags:    tags,
+		Buckets: buckets,
+		Count:   cumulative + h.counts[len(h.bounds)].Load(),
+		Sum:     h.sum.Load(),
+	}
+}
+
+func histogramKey(name string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var sb strings.Builder
+
+	sb.WriteString(name)
+
+	for _, k := range keys {
+		sb.WriteByte(0)
+		sb.WriteString(k)
+		sb.WriteByte(0)
+		sb.WriteString(tags[k])
+	}
+
+	return sb.String()
+}
+
+func prometheusName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == ':' {
+			return r
+		}
+
+		return '_'
+	}, name)
+}
+
+func prometheusLabels(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	labels := ""
+	for _, k := range keys {
+		labels = appendPrometheusLabel(labels, prometheusName(k), tags[k])
+	}
+
+	return labels
+}
+
+var prometheusLabelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func appendPrometheusLabel(labels, key, value string) string {
+	label := key + `="` + prometheusLabelValueReplacer.Replace(value) + `"`
+	if labels == "" {
+		return label
+	}
+
+	return labels + "," + label
+}
@@ -0,0 +1,164 @@
+package cbanalytics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryMeterHistogram(t *testing.T) {
+	meter := NewInMemoryMeter(NewInMemoryMeterOptions().SetBuckets("test", []uint64{100, 10, 1000}))
+
+	recorder, err := meter.ValueRecorder("test", map[string]string{"a": "b"})
+	require.NoError(t, err)
+
+	for _, val := range []uint64{5, 10, 11, 500, 5000} {
+		recorder.RecordValue(val)
+	}
+
+	// The same name and tags must return the same histogram.
+	recorder, err = meter.ValueRecorder("test", map[string]string{"a": "b"})
+	require.NoError(t, err)
+	recorder.RecordValue(1)
+
+	snapshots := meter.Snapshot()
+	require.Len(t, snapshots, 1)
+
+	snapshot := snapshots[0]
+	assert.Equal(t, "test", snapshot.Name)
+	assert.Equal(t, map[string]string{"a": "b"}, snapshot.Tags)
+	assert.Equal(t, uint64(6), snapshot.Count)
+	assert.Equal(t, uint64(5527), snapshot.Sum)
+	assert.Equal(t, []HistogramBucket{
+		{UpperBound: 10, Count: 3},
+		{UpperBound: 100, Count: 4},
+		{UpperBound: 1000, Count: 5},
+	}, snapshot.Buckets)
+}
+
+func TestInMemoryMeterWritePrometheus(t *testing.T) {
+	meter := NewInMemoryMeter(NewInMemoryMeterOptions().SetBuckets(MetricOperationRetries, []uint64{0, 1}))
+
+	recorder, err := meter.ValueRecorder(MetricOperationRetries, map[string]string{
+		MetricTagService:   "analytics",
+		MetricTagOperation: "execute_query",
+	})
+	require.NoError(t, err)
+
+	recorder.RecordValue(0)
+	recorder.RecordValue(3)
+
+	var buf bytes.Buffer
+	require.NoError(t, meter.WritePrometheus(&buf))
+
+	assert.Equal(t, `# TYPE db_couchbase_operations_retries histogram
+db_couchbase_operations_retries_bucket{db_couchbase_service="analytics",db_operation="execute_query",le="0"} 1
+db_couchbase_operations_retries_bucket{db_couchbase_service="analytics",db_operation="execute_query",le="1"} 1
+db_couchbase_operations_retries_bucket{db_couchbase_service="analytics",db_operation="execute_query",le="+Inf"} 2
+db_couchbase_operations_retries_sum{db_couchbase_service="analytics",db_operation="execute_query"} 3
+db_couchbase_operations_retries_count{db_couchbase_service="analytics",db_operation="execute_query"} 2
+`, buf.String())
+}
+
+func TestMetricOutcome(t *testing.T) {
+	assert.Equal(t, MetricOutcomeSuccess, metricOutcome(nil))
+	assert.Equal(t, MetricOutcomeTimeout, metricOutcome(newAnalyticsError(ErrTimeout, "", "", 0, 0)))
+	assert.Equal(t, MetricOutcomeCanceled, metricOutcome(context.Canceled))
+	assert.Equal(t, MetricOutcomeInvalidArgument, metricOutcome(invalidArgumentError{
+		ArgumentName: "a",
+		Reason:       "b",
+	}))
+	assert.Equal(t, MetricOutcomeQueryError, metricOutcome(newQueryError(nil, "", "", 200, 24045, "msg", 0)))
+	assert.Equal(t, MetricOutcomeOther, metricOutcome(errors.New("unknown"))) //nolint:err113
+}
+
+func findSnapshot(snapshots []HistogramSnapshot, name string) *HistogramSnapshot {
+	for i := range snapshots {
+		if snapshots[i].Name == name {
+			return &snapshots[i]
+		}
+	}
+
+	return nil
+}
+
+func TestQueryRecordsMetrics(t *testing.T) {
+	var attempt int32
+
+	body := `{"requestID":"1","results":[1,2,3],"status":"success"}`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if atomic.AddInt32(&attempt, 1) == 1 {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"errors":[{"code":23007,"msg":"busy","retriable":true}],"status":"fatal"}`))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	meter := NewInMemoryMeter()
+	client := newTestHTTPQueryClient(t, srv.Listener.Addr().String(), meter)
+
+	res, err := client.Query(context.Background(), "SELECT 1", NewQueryOptions())
+	require.NoError(t, err)
+
+	// Nothing is recorded until the stream has been consumed.
+	assert.Empty(t, meter.Snapshot())
+
+	for res.NextRow() != nil {
+	}
+
+	require.NoError(t, res.Err())
+
+	snapshots := meter.Snapshot()
+
+	duration := findSnapshot(snapshots, MetricOperationDuration)
+	require.NotNil(t, duration)
+	assert.Equal(t, uint64(1), duration.Count)
+	assert.Equal(t, map[string]string{
+		MetricTagService:   "analytics",
+		MetricTagOperation: SpanNameExecuteQuery,
+		MetricTagOutcome:   MetricOutcomeSuccess,
+	}, duration.Tags)
+
+	retries := findSnapshot(snapshots, MetricOperationRetries)
+	require.NotNil(t, retries)
+	assert.Equal(t, uint64(1), retries.Sum)
+
+	rows := findSnapshot(snapshots, MetricOperationRows)
+	require.NotNil(t, rows)
+	assert.Equal(t, uint64(3), rows.Sum)
+
+	received := findSnapshot(snapshots, MetricOperationBytesReceived)
+	require.NotNil(t, received)
+	assert.GreaterOrEqual(t, received.Sum, uint64(len(body)))
+}
+
+func TestQueryRecordsErrorOutcome(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"errors":[{"code":24045,"msg":"syntax error"}],"status":"fatal"}`))
+	}))
+	defer srv.Close()
+
+	meter := NewInMemoryMeter()
+	client := newTestHTTPQueryClient(t, srv.Listener.Addr().String(), meter)
+
+	_, err := client.Query(context.Background(), "SELEC 1", NewQueryOptions())
+	require.ErrorIs(t, err, ErrQuery)
+
+	duration := findSnapshot(meter.Snapshot(), MetricOperationDuration)
+	require.NotNil(t, duration)
+	assert.Equal(t, MetricOutcomeQueryError, duration.Tags[MetricTagOutcome])
+}
@@ -4,9 +4,25 @@ import (
 	"context"
 )
 
+// Querier executes queries. It is implemented by both Cluster and Scope, allowing code to be written which
+// works against either, and allowing them to be substituted in tests.
+type Querier interface {
+	// ExecuteQuery executes the query statement on the server.
+	ExecuteQuery(ctx context.Context, statement string, opts ...*QueryOptions) (*QueryResult, error)
+
+	// StartQuery starts execution of a deferred query statement on the server.
+	StartQuery(ctx context.Context, statement string, opts ...*StartQueryOptions) (*QueryHandle, error)
+}
+
+var _ Querier = (*Cluster)(nil)
+
+var _ Querier = (*Scope)(nil)
+
 // ExecuteQuery executes the query statement on the server.
 // When ExecuteQuery is called with no context.Context, or a context.Context with no Deadline, then
 // the Cluster level QueryTimeout will be applied.
+// If ctx is cancelled while the query is executing or its results are streaming, the query is also
+// cancelled on the server, on a best effort basis.
 func (c *Cluster) ExecuteQuery(ctx context.Context, statement string, opts ...*QueryOptions) (*QueryResult, error) {
 	if ctx == nil {
 		ctx = context.Background()
@@ -31,9 +47,24 @@ func (c *Cluster) StartQuery(ctx context.Context, statement string, opts ...*Sta
 	return c.client.QueryClient().StartQuery(ctx, statement, startOpts) //nolint:wrapcheck
 }
 
+// QueryHandleFromToken recreates a QueryHandle from a token created by QueryHandle.MarshalText, binding it to
+// this Cluster. The token must have been created by a handle from a cluster with the same endpoint.
+func (c *Cluster) QueryHandleFromToken(token string) (*QueryHandle, error) {
+	return c.client.QueryClient().QueryHandleFromToken(token) //nolint:wrapcheck
+}
+
+// ResultHandleFromToken recreates a QueryResultHandle from a token created by QueryResultHandle.MarshalText,
+// binding it to this Cluster. The token must have been created by a handle from a cluster with the same
+// endpoint. The results are decoded using the Cluster level Unmarshaler unless another is given to FetchResults.
+func (c *Cluster) ResultHandleFromToken(token string) (*QueryResultHandle, error) {
+	return c.client.QueryClient().ResultHandleFromToken(token) //nolint:wrapcheck
+}
+
 // ExecuteQuery executes the query statement on the server, tying the query context to this Scope.
 // When ExecuteQuery is called with no context.Context, or a context.Context with no Deadline, then
 // the Cluster level QueryTimeout will be applied.
+// If ctx is cancelled while the query is executing or its results are streaming, the query is also
+// cancelled on the server, on a best effort basis.
 func (s *Scope) ExecuteQuery(ctx context.Context, statement string, opts ...*QueryOptions) (*QueryResult, error) {
 	if ctx == nil {
 		ctx = context.Background()
@@ -68,6 +99,9 @@ func mergeQueryOptions(opts ...*QueryOptions) *QueryOptions {
 		Raw:                  nil,
 		Unmarshaler:          nil,
 		MaxRetries:           nil,
+		RetryStrategy:        nil,
+		CancelOnClose:        nil,
+		PlanOptions:          nil,
 	}
 
 	for _, opt := range opts {
@@ -106,6 +140,18 @@ func mergeQueryOptions(opts ...*QueryOptions) *QueryOptions {
 		if opt.MaxRetries != nil {
 			queryOpts.MaxRetries = opt.MaxRetries
 		}
+
+		if opt.RetryStrategy != nil {
+			queryOpts.RetryStrategy = opt.RetryStrategy
+		}
+
+		if opt.CancelOnClose != nil {
+			queryOpts.CancelOnClose = opt.CancelOnClose
+		}
+
+		if opt.PlanOptions != nil {
+			queryOpts.PlanOptions = opt.PlanOptions
+		}
 	}
 
 	return queryOpts
@@ -3,30 +3,245 @@ package cbanalytics
 import (
 	"context"
 	"fmt"
+	"time"
+)
+
+const (
+	defaultWaitInitialInterval = 100 * time.Millisecond
+	defaultWaitMaxInterval     = 5 * time.Second
+	defaultWaitBackoffFactor   = 2
 )
 
 // QueryHandle represents an asynchronous query handle that can be used to check status,
 // retrieve results, cancel, or discard a deferred query.
+//
+// A QueryHandle can be persisted using MarshalText or MarshalJSON, and later resumed, possibly in another
+// process, using Cluster.QueryHandleFromToken.
 type QueryHandle struct {
 	handle    string
 	requestID string
+	endpoint  string
 	provider  queryHandleProvider
 }
 
+// MarshalText encodes the handle as an opaque token which can be passed to Cluster.QueryHandleFromToken.
+func (qh *QueryHandle) MarshalText() ([]byte, error) {
+	return encodeHandleToken(jsonHandleToken{
+		Version:   handleTokenVersion,
+		Kind:      handleTokenKindQuery,
+		Endpoint:  qh.endpoint,
+		Handle:    qh.handle,
+		RequestID: qh.requestID,
+	})
+}
+
+// MarshalJSON encodes the handle as a JSON string containing the token returned by MarshalText.
+func (qh *QueryHandle) MarshalJSON() ([]byte, error) {
+	return marshalTextJSON(qh)
+}
+
 // FetchStatus fetches the current status of the deferred query from the server.
 func (qh *QueryHandle) FetchStatus(ctx context.Context) (*QueryStatus, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	return qh.provider.fetchHandleStatus(ctx, qh.handle)
+	return qh.provider.fetchHandleStatus(ctx, qh.handle, qh.endpoint)
+}
+
+// Wait polls the status of the deferred query until its results are ready, returning the QueryResultHandle for
+// them. The interval between status checks starts at WaitOptions.InitialInterval and grows by
+// WaitOptions.BackoffFactor after each check, up to WaitOptions.MaxInterval.
+// If the query fails then the error reported by the server is returned.
+// Wait returns when ctx is done if the results are not ready by then, it does not cancel the query.
+func (qh *QueryHandle) Wait(ctx context.Context, opts ...*WaitOptions) (*QueryResultHandle, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	waitOpts := mergeWaitOptions(opts...)
+
+	interval := defaultWaitInitialInterval
+	if waitOpts.InitialInterval != nil {
+		interval = *waitOpts.InitialInterval
+	}
+
+	maxInterval := defaultWaitMaxInterval
+	if waitOpts.MaxInterval != nil {
+		maxInterval = *waitOpts.MaxInterval
+	}
+
+	backoffFactor := float64(defaultWaitBackoffFactor)
+	if waitOpts.BackoffFactor != nil {
+		backoffFactor = *waitOpts.BackoffFactor
+	}
+
+	if interval <= 0 {
+		return nil, invalidArgumentError{
+			ArgumentName: "InitialInterval",
+			Reason:       "must be greater than 0",
+		}
+	}
+
+	if maxInterval < interval {
+		return nil, invalidArgumentError{
+			ArgumentName: "MaxInterval",
+			Reason:       "must not be less than InitialInterval",
+		}
+	}
+
+	if backoffFactor < 1 {
+		return nil, invalidArgumentError{
+			ArgumentName: "BackoffFactor",
+			Reason:       "must be at least 1",
+		}
+	}
+
+	for {
+		status, err := qh.FetchStatus(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if status.ResultsReady() {
+			return status.resultHandle, nil
+		}
+
+		if status.State().isFailed() {
+			return nil, status.err
+		}
+
+		if waitOpts.Progress != nil {
+			waitOpts.Progress(status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, newAnalyticsError(ctx.Err(), "", "", 0, 0).
+				withMessage("query results were not ready before the context was done")
+		case <-time.After(interval):
+		}
+
+		interval = time.Duration(float64(interval) * backoffFactor)
+		if interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
+
+// QueryStatusState is the state of a deferred query.
+type QueryStatusState uint
+
+const (
+	// QueryStatusStateUnknown indicates that the server reported a state which is not recognised.
+	QueryStatusStateUnknown QueryStatusState = iota + 1
+	// QueryStatusStateQueued indicates that the query is waiting to be executed.
+	QueryStatusStateQueued
+	// QueryStatusStateRunning indicates that the query is executing.
+	QueryStatusStateRunning
+	// QueryStatusStateSuccess indicates that the query completed and its results are ready.
+	QueryStatusStateSuccess
+	// QueryStatusStateFailed indicates that the query ended with an error.
+	QueryStatusStateFailed
+	// QueryStatusStateTimeout indicates that the query ended because it exceeded its timeout.
+	QueryStatusStateTimeout
+	// QueryStatusStateCancelled indicates that the query was cancelled before it completed.
+	QueryStatusStateCancelled
+)
+
+// parseQueryStatusState maps a status reported by the server onto a QueryStatusState.
+func parseQueryStatusState(status string) QueryStatusState {
+	switch status {
+	case "queued":
+		return QueryStatusStateQueued
+	case "running":
+		return QueryStatusStateRunning
+	case "success":
+		return QueryStatusStateSuccess
+	case "failed", "fatal", "errors":
+		return QueryStatusStateFailed
+	case "timeout":
+		return QueryStatusStateTimeout
+	case "cancelled", "canceled", "aborted":
+		return QueryStatusStateCancelled
+	default:
+		return QueryStatusStateUnknown
+	}
+}
+
+// String returns the name of the state.
+func (s QueryStatusState) String() string {
+	switch s {
+	case QueryStatusStateQueued:
+		return "queued"
+	case QueryStatusStateRunning:
+		return "running"
+	case QueryStatusStateSuccess:
+		return "success"
+	case QueryStatusStateFailed:
+		return "failed"
+	case QueryStatusStateTimeout:
+		return "timeout"
+	case QueryStatusStateCancelled:
+		return "cancelled"
+	default:
+		return "unknown"
+	}
+}
+
+// isFailed returns whether the state means that the query has ended without results.
+func (s QueryStatusState) isFailed() bool {
+	return s == QueryStatusStateFailed || s == QueryStatusStateTimeout || s == QueryStatusStateCancelled
 }
 
 // QueryStatus represents the status of a deferred query.
 type QueryStatus struct {
-	status       string
-	metrics      string
-	resultHandle *QueryResultHandle
+	status        string
+	metrics       string
+	createdAt     time.Time
+	parsedMetrics QueryMetrics
+	resultHandle  *QueryResultHandle
+
+	// err is the error for a query which has ended without results, see QueryStatusState.isFailed.
+	err error
+}
+
+// State returns the state of the query.
+func (qs *QueryStatus) State() QueryStatusState {
+	return parseQueryStatusState(qs.status)
+}
+
+// RawState returns the state of the query exactly as reported by the server.
+func (qs *QueryStatus) RawState() string {
+	return qs.status
+}
+
+// CreatedAt returns the time at which the server received the query, or the zero time if the server did not
+// report it.
+func (qs *QueryStatus) CreatedAt() time.Time {
+	return qs.createdAt
+}
+
+// ElapsedTime returns how long the query has been running for, as reported by the server.
+// If the server did not report it while the query is running then the time since CreatedAt is used instead.
+// Otherwise, or if CreatedAt is also unknown, 0 is returned.
+func (qs *QueryStatus) ElapsedTime() time.Duration {
+	if qs.parsedMetrics.ElapsedTime > 0 {
+		return qs.parsedMetrics.ElapsedTime
+	}
+
+	// Once the query has stopped running, the time since it was created is not how long it ran for.
+	if qs.State() != QueryStatusStateRunning || qs.createdAt.IsZero() {
+		return 0
+	}
+
+	return time.Since(qs.createdAt)
+}
+
+// Metrics returns the metrics reported by the server for the query so far.
+// Any metrics which were not reported are left as their zero value.
+func (qs *QueryStatus) Metrics() QueryMetrics {
+	return qs.parsedMetrics
 }
 
 // ResultsReady returns true if the query results are ready to be fetched.
@@ -59,12 +274,33 @@ func (qh *QueryHandle) Cancel(ctx context.Context) error {
 }
 
 // QueryResultHandle provides access to the results of a completed deferred query.
+//
+// A QueryResultHandle can be persisted using MarshalText or MarshalJSON, and later resumed, possibly in another
+// process, using Cluster.ResultHandleFromToken.
 type QueryResultHandle struct {
 	handle      string
+	endpoint    string
 	provider    queryHandleProvider
 	unmarshaler Unmarshaler
 }
 
+// MarshalText encodes the handle as an opaque token which can be passed to Cluster.ResultHandleFromToken.
+// The unmarshaler is not part of the token.
+func (qhr *QueryResultHandle) MarshalText() ([]byte, error) {
+	return encodeHandleToken(jsonHandleToken{
+		Version:   handleTokenVersion,
+		Kind:      handleTokenKindResult,
+		Endpoint:  qhr.endpoint,
+		Handle:    qhr.handle,
+		RequestID: "",
+	})
+}
+
+// MarshalJSON encodes the handle as a JSON string containing the token returned by MarshalText.
+func (qhr *QueryResultHandle) MarshalJSON() ([]byte, error) {
+	return marshalTextJSON(qhr)
+}
+
 // FetchResults streams all results from the completed deferred query.
 func (qhr *QueryResultHandle) FetchResults(ctx context.Context, opts ...*FetchResultsOptions) (*QueryResult, error) {
 	if ctx == nil {
@@ -0,0 +1,312 @@
+package cbanalytics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testStatusHandle = "/api/v1/request/status/1/req-1"
+
+// scriptedServer is a server which responds to requests for each path with each of its bodies in turn,
+// repeating the last one once they have all been sent, for responses which analyticstest cannot script.
+// Requests for any other path receive a 404.
+type scriptedServer struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	bodies   map[string][]string
+	requests map[string]int
+}
+
+func newScriptedServer(t *testing.T, bodies map[string][]string) *scriptedServer {
+	t.Helper()
+
+	srv := &scriptedServer{ //nolint:exhaustruct
+		bodies:   bodies,
+		requests: make(map[string]int),
+	}
+
+	srv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		srv.lock.Lock()
+		responses := srv.bodies[r.URL.Path]
+		srv.requests[r.URL.Path]++
+		n := srv.requests[r.URL.Path]
+		srv.lock.Unlock()
+
+		if len(responses) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		if n > len(responses) {
+			n = len(responses)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(responses[n-1]))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// Requests returns the number of requests received for the path.
+func (s *scriptedServer) Requests(path string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.requests[path]
+}
+
+// Addr returns the host:port that the server is listening on.
+func (s *scriptedServer) Addr() string {
+	return s.Listener.Addr().String()
+}
+
+func newTestQueryHandle(t *testing.T, srv *scriptedServer) *QueryHandle {
+	t.Helper()
+
+	return &QueryHandle{
+		handle:    testStatusHandle,
+		requestID: "req-1",
+		endpoint:  srv.Addr(),
+		provider:  newTestHTTPQueryClient(t, srv.Addr(), NewNoopMeter()),
+	}
+}
+
+func TestQueryHandleWait(t *testing.T) {
+	srv := newScriptedServer(t, map[string][]string{testStatusHandle: {
+		`{"status":"queued"}`,
+		`{"status":"running","metrics":{"elapsedTime":"1ms"}}`,
+		`{"status":"success","handle":"/api/v1/request/result/1/req-1"}`,
+	}})
+
+	var progress []string
+
+	opts := NewWaitOptions().
+		SetInitialInterval(time.Millisecond).
+		SetMaxInterval(2 * time.Millisecond).
+		SetProgress(func(status *QueryStatus) {
+			progress = append(progress, status.status)
+		})
+
+	resultHandle, err := newTestQueryHandle(t, srv).Wait(context.Background(), opts)
+	require.NoError(t, err)
+
+	assert.Equal(t, "/api/v1/request/result/1/req-1", resultHandle.handle)
+	assert.Equal(t, []string{"queued", "running"}, progress)
+	assert.Equal(t, 3, srv.Requests(testStatusHandle))
+}
+
+func TestQueryHandleFetchStatusParsed(t *testing.T) {
+	srv := newScriptedServer(t, map[string][]string{testStatusHandle: {
+		`{"status":"running","createdAt":"2024-05-01T10:00:00.5Z",` +
+			`"metrics":{"elapsedTime":"1.5s","executionTime":"1.2s","resultCount":10,"resultSize":200,"processedObjects":30}}`,
+	}})
+
+	status, err := newTestQueryHandle(t, srv).FetchStatus(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, QueryStatusStateRunning, status.State())
+	assert.Equal(t, "running", status.RawState())
+	assert.False(t, status.ResultsReady())
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 0, 500000000, time.UTC), status.CreatedAt())
+	assert.Equal(t, 1500*time.Millisecond, status.ElapsedTime())
+	assert.Equal(t, QueryMetrics{
+		ElapsedTime:      1500 * time.Millisecond,
+		ExecutionTime:    1200 * time.Millisecond,
+		ResultCount:      10,
+		ResultSize:       200,
+		ProcessedObjects: 30,
+	}, status.Metrics())
+}
+
+func TestQueryHandleFetchStatusCreatedAtNotTimestamp(t *testing.T) {
+	srv := newScriptedServer(t, map[string][]string{testStatusHandle: {`{"status":"running","createdAt":1714557600000}`}})
+
+	status, err := newTestQueryHandle(t, srv).FetchStatus(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, QueryStatusStateRunning, status.State())
+	assert.True(t, status.CreatedAt().IsZero())
+	assert.Zero(t, status.ElapsedTime())
+}
+
+func TestQueryStatusElapsedTimeFallback(t *testing.T) {
+	createdAt := time.Now().Add(-time.Minute)
+
+	running := &QueryStatus{status: "running", createdAt: createdAt} //nolint:exhaustruct
+	assert.GreaterOrEqual(t, running.ElapsedTime(), time.Minute)
+
+	for _, state := range []string{"queued", "success", "fatal", "timeout"} {
+		notRunning := &QueryStatus{status: state, createdAt: createdAt} //nolint:exhaustruct
+		assert.Zero(t, notRunning.ElapsedTime(), state)
+	}
+}
+
+func TestQueryStatusState(t *testing.T) {
+	tests := map[string]QueryStatusState{
+		"queued":    QueryStatusStateQueued,
+		"running":   QueryStatusStateRunning,
+		"success":   QueryStatusStateSuccess,
+		"fatal":     QueryStatusStateFailed,
+		"timeout":   QueryStatusStateTimeout,
+		"cancelled": QueryStatusStateCancelled,
+		"paused":    QueryStatusStateUnknown,
+	}
+
+	for raw, expected := range tests {
+		state := parseQueryStatusState(raw)
+		assert.Equal(t, expected, state, raw)
+	}
+
+	assert.Equal(t, "cancelled", QueryStatusStateCancelled.String())
+	assert.Equal(t, "unknown", QueryStatusStateUnknown.String())
+}
+
+func TestQueryHandleWaitQueryError(t *testing.T) {
+	srv := newScriptedServer(t, map[string][]string{testStatusHandle: {
+		`{"status":"running"}`,
+		`{"status":"fatal","errors":[{"code":24045,"msg":"syntax error"}]}`,
+	}})
+
+	// The handle was issued by an endpoint other than the one its status is fetched from.
+	handle := newTestQueryHandle(t, srv)
+	handle.endpoint = "issuer.example.com:18095"
+
+	_, err := handle.Wait(context.Background(), NewWaitOptions().SetInitialInterval(time.Millisecond))
+	require.ErrorIs(t, err, ErrQuery)
+	require.ErrorIs(t, err, ErrDatasetNotFound)
+
+	var qErr *QueryError
+	require.ErrorAs(t, err, &qErr)
+	assert.Equal(t, 24045, qErr.Code())
+	assert.Equal(t, "issuer.example.com:18095", qErr.cause.Endpoint())
+}
+
+func TestQueryHandleWaitFailedStatus(t *testing.T) {
+	srv := newScriptedServer(t, map[string][]string{testStatusHandle: {`{"status":"timeout"}`}})
+
+	_, err := newTestQueryHandle(t, srv).Wait(context.Background())
+	require.ErrorIs(t, err, ErrAnalytics)
+	assert.Contains(t, err.Error(), "timeout")
+	assert.Contains(t, err.Error(), testStatusHandle)
+
+	var aErr *AnalyticsError
+	require.ErrorAs(t, err, &aErr)
+	assert.Equal(t, srv.Addr(), aErr.Endpoint())
+}
+
+func TestQueryHandleWaitContextDone(t *testing.T) {
+	srv := newScriptedServer(t, map[string][]string{testStatusHandle: {`{"status":"running"}`}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := newTestQueryHandle(t, srv).Wait(ctx, NewWaitOptions().SetInitialInterval(10*time.Millisecond))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestQueryHandleWaitInvalidOptions(t *testing.T) {
+	srv := newScriptedServer(t, map[string][]string{testStatusHandle: {`{"status":"running"}`}})
+	handle := newTestQueryHandle(t, srv)
+
+	for _, opts := range []*WaitOptions{
+		NewWaitOptions().SetInitialInterval(0),
+		NewWaitOptions().SetInitialInterval(time.Second).SetMaxInterval(time.Millisecond),
+		NewWaitOptions().SetBackoffFactor(0.5),
+	} {
+		_, err := handle.Wait(context.Background(), opts)
+		require.ErrorIs(t, err, ErrInvalidArgument)
+	}
+
+	assert.Equal(t, 0, srv.Requests(testStatusHandle))
+}
+
+func TestQueryHandleTokenRoundTrip(t *testing.T) {
+	srv := newScriptedServer(t, map[string][]string{
+		"/api/v1/request": {`{"requestID":"req-1","handle":"` + testStatusHandle + `","status":"running"}`},
+		testStatusHandle:  {`{"status":"success","handle":"/api/v1/request/result/1/req-1"}`},
+	})
+
+	handle, err := newTestHTTPQueryClient(t, srv.Addr(), NewNoopMeter()).
+		StartQuery(context.Background(), "SELECT 1", NewStartQueryOptions())
+	require.NoError(t, err)
+
+	data, err := json.Marshal(struct {
+		Handle *QueryHandle `json:"handle"`
+	}{Handle: handle})
+	require.NoError(t, err)
+
+	var persisted struct {
+		Handle string `json:"handle"`
+	}
+	require.NoError(t, json.Unmarshal(data, &persisted))
+
+	// The handle is resumed by a different client, as if in another process.
+	resumer := newTestHTTPQueryClient(t, srv.Addr(), NewNoopMeter())
+
+	resumed, err := resumer.QueryHandleFromToken(persisted.Handle)
+	require.NoError(t, err)
+	assert.Equal(t, handle.handle, resumed.handle)
+	assert.Equal(t, "req-1", resumed.requestID)
+
+	status, err := resumed.FetchStatus(context.Background())
+	require.NoError(t, err)
+
+	resultHandle, err := status.ResultHandle()
+	require.NoError(t, err)
+
+	token, err := resultHandle.MarshalText()
+	require.NoError(t, err)
+
+	resumedResult, err := resumer.ResultHandleFromToken(string(token))
+	require.NoError(t, err)
+	assert.Equal(t, "/api/v1/request/result/1/req-1", resumedResult.handle)
+	assert.Equal(t, srv.Addr(), resumedResult.endpoint)
+}
+
+func TestQueryHandleFromTokenInvalid(t *testing.T) {
+	srv := newScriptedServer(t, map[string][]string{testStatusHandle: {`{"status":"running"}`}})
+	handle := newTestQueryHandle(t, srv)
+
+	token, err := handle.MarshalText()
+	require.NoError(t, err)
+
+	other := newTestHTTPQueryClient(t, "127.0.0.1:1", NewNoopMeter())
+
+	_, err = other.QueryHandleFromToken(string(token))
+	require.ErrorIs(t, err, ErrInvalidArgument)
+
+	client := newTestHTTPQueryClient(t, srv.Addr(), NewNoopMeter())
+
+	_, err = client.ResultHandleFromToken(string(token))
+	require.ErrorIs(t, err, ErrInvalidArgument)
+
+	_, err = client.QueryHandleFromToken("not a token")
+	require.ErrorIs(t, err, ErrInvalidArgument)
+
+	_, err = client.QueryHandleFromToken(string(token))
+	require.NoError(t, err)
+}
+
+func TestStartQueryMissingHandle(t *testing.T) {
+	srv := newScriptedServer(t, map[string][]string{"/api/v1/request": {`{"requestID":"req-1","status":"running"}`}})
+
+	_, err := newTestHTTPQueryClient(t, srv.Addr(), NewNoopMeter()).
+		StartQuery(context.Background(), "SELECT 1", NewStartQueryOptions())
+	require.ErrorIs(t, err, ErrAnalytics)
+
+	var aErr *AnalyticsError
+	require.ErrorAs(t, err, &aErr)
+	assert.Equal(t, srv.Addr(), aErr.Endpoint())
+}
@@ -0,0 +1,86 @@
+package cbanalytics
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	handleTokenVersion    = 1
+	handleTokenKindQuery  = "query"
+	handleTokenKindResult = "result"
+)
+
+// jsonHandleToken is the content of the tokens that QueryHandle and QueryResultHandle are marshalled to.
+type jsonHandleToken struct {
+	Version   int    `json:"v"`
+	Kind      string `json:"kind"`
+	Endpoint  string `json:"endpoint"`
+	Handle    string `json:"handle"`
+	RequestID string `json:"requestID,omitempty"`
+}
+
+// encodeHandleToken encodes a token as URL safe base64 so that it can be stored or passed around as plain text.
+func encodeHandleToken(token jsonHandleToken) ([]byte, error) {
+	raw, err := json.Marshal(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal handle token: %w", err)
+	}
+
+	encoded := make([]byte, base64.RawURLEncoding.EncodedLen(len(raw)))
+	base64.RawURLEncoding.Encode(encoded, raw)
+
+	return encoded, nil
+}
+
+func decodeHandleToken(token string) (*jsonHandleToken, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, invalidArgumentError{
+			ArgumentName: "token",
+			Reason:       "not a valid handle token",
+		}
+	}
+
+	var parsed jsonHandleToken
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return nil, invalidArgumentError{
+			ArgumentName: "token",
+			Reason:       "not a valid handle token",
+		}
+	}
+
+	if parsed.Version != handleTokenVersion {
+		return nil, invalidArgumentError{
+			ArgumentName: "token",
+			Reason:       fmt.Sprintf("unsupported handle token version %d", parsed.Version),
+		}
+	}
+
+	if parsed.Handle == "" || parsed.Endpoint == "" {
+		return nil, invalidArgumentError{
+			ArgumentName: "token",
+			Reason:       "handle token is incomplete",
+		}
+	}
+
+	if parsed.Kind == handleTokenKindQuery && parsed.RequestID == "" {
+		return nil, invalidArgumentError{
+			ArgumentName: "token",
+			Reason:       "query handle token has no request id",
+		}
+	}
+
+	return &parsed, nil
+}
+
+func marshalTextJSON(m encoding.TextMarshaler) ([]byte, error) {
+	text, err := m.MarshalText()
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return json.Marshal(string(text)) //nolint:wrapcheck
+}
@@ -1,5 +1,7 @@
 package cbanalytics
 
+import "time"
+
 // QueryScanConsistency indicates the level of data consistency desired for an analytics query.
 type QueryScanConsistency uint
 
@@ -10,7 +12,72 @@ const (
 	QueryScanConsistencyRequestPlus
 )
 
+// QueryPlanFormat specifies the format that query plans are returned in.
+// VOLATILE: This API is subject to change at any time.
+type QueryPlanFormat uint
+
+const (
+	// QueryPlanFormatJSON returns query plans as a tree of JSON objects, which can be parsed with ParseQueryPlan.
+	QueryPlanFormatJSON QueryPlanFormat = iota + 1
+
+	// QueryPlanFormatString returns query plans as human readable text.
+	QueryPlanFormatString
+)
+
+// QueryPlanOptions specifies which query plans the server returns alongside the results of a query. The plans
+// are available from QueryMetadata.Plans.
+// VOLATILE: This API is subject to change at any time.
+type QueryPlanOptions struct {
+	// Format specifies the format that plans are returned in.
+	// Default = QueryPlanFormatJSON
+	Format *QueryPlanFormat
+
+	// LogicalPlan specifies whether the logical plan, before optimization, is returned.
+	LogicalPlan *bool
+
+	// OptimizedLogicalPlan specifies whether the optimized logical plan is returned.
+	OptimizedLogicalPlan *bool
+}
+
+// NewQueryPlanOptions creates a new instance of QueryPlanOptions.
+// VOLATILE: This API is subject to change at any time.
+func NewQueryPlanOptions() *QueryPlanOptions {
+	return &QueryPlanOptions{
+		Format:               nil,
+		LogicalPlan:          nil,
+		OptimizedLogicalPlan: nil,
+	}
+}
+
+// SetFormat sets the Format field in QueryPlanOptions.
+// VOLATILE: This API is subject to change at any time.
+func (opts *QueryPlanOptions) SetFormat(format QueryPlanFormat) *QueryPlanOptions {
+	opts.Format = &format
+
+	return opts
+}
+
+// SetLogicalPlan sets the LogicalPlan field in QueryPlanOptions.
+// VOLATILE: This API is subject to change at any time.
+func (opts *QueryPlanOptions) SetLogicalPlan(logicalPlan bool) *QueryPlanOptions {
+	opts.LogicalPlan = &logicalPlan
+
+	return opts
+}
+
+// SetOptimizedLogicalPlan sets the OptimizedLogicalPlan field in QueryPlanOptions.
+// VOLATILE: This API is subject to change at any time.
+func (opts *QueryPlanOptions) SetOptimizedLogicalPlan(optimizedLogicalPlan bool) *QueryPlanOptions {
+	opts.OptimizedLogicalPlan = &optimizedLogicalPlan
+
+	return opts
+}
+
 // QueryOptions is the set of options available to an Analytics query.
+//
+// Analytics has no prepared statements. Every query is compiled by the server from its statement text when it is
+// executed. Values are best passed using PositionalParameters or NamedParameters, as then they are sent as JSON
+// and never need escaping into the statement.
 type QueryOptions struct {
 	// ClientContextID is an optional identifier for the query.
 	ClientContextID *string
@@ -37,6 +104,21 @@ type QueryOptions struct {
 	// This includes connection attempts.
 	// VOLATILE: This API is subject to change at any time.
 	MaxRetries *uint32
+
+	// RetryStrategy specifies the strategy used to decide whether, and when, to retry a failed request.
+	// This overrides the RetryStrategy set on ClusterOptions.
+	// VOLATILE: This API is subject to change at any time.
+	RetryStrategy RetryStrategy
+
+	// CancelOnClose specifies whether closing the QueryResult before every row has been read should also
+	// cancel the query on the server, so that the cluster stops producing rows which will not be read.
+	// The cancellation is best effort, any failure to cancel is logged rather than returned.
+	// VOLATILE: This API is subject to change at any time.
+	CancelOnClose *bool
+
+	// PlanOptions specifies which query plans the server returns alongside the results.
+	// VOLATILE: This API is subject to change at any time.
+	PlanOptions *QueryPlanOptions
 }
 
 // NewQueryOptions creates a new instance of QueryOptions.
@@ -50,6 +132,9 @@ func NewQueryOptions() *QueryOptions {
 		Raw:                  nil,
 		Unmarshaler:          nil,
 		MaxRetries:           nil,
+		RetryStrategy:        nil,
+		CancelOnClose:        nil,
+		PlanOptions:          nil,
 	}
 }
 
@@ -110,6 +195,30 @@ func (opts *QueryOptions) SetMaxRetries(maxRetries uint32) *QueryOptions {
 	return opts
 }
 
+// SetRetryStrategy sets the RetryStrategy field in QueryOptions.
+// VOLATILE: This API is subject to change at any time.
+func (opts *QueryOptions) SetRetryStrategy(retryStrategy RetryStrategy) *QueryOptions {
+	opts.RetryStrategy = retryStrategy
+
+	return opts
+}
+
+// SetCancelOnClose sets the CancelOnClose field in QueryOptions.
+// VOLATILE: This API is subject to change at any time.
+func (opts *QueryOptions) SetCancelOnClose(cancelOnClose bool) *QueryOptions {
+	opts.CancelOnClose = &cancelOnClose
+
+	return opts
+}
+
+// SetPlanOptions sets the PlanOptions field in QueryOptions.
+// VOLATILE: This API is subject to change at any time.
+func (opts *QueryOptions) SetPlanOptions(planOptions *QueryPlanOptions) *QueryOptions {
+	opts.PlanOptions = planOptions
+
+	return opts
+}
+
 // StartQueryOptions is the set of options available to an Analytics query.
 type StartQueryOptions struct {
 	// ClientContextID is an optional identifier for the query.
@@ -134,6 +243,11 @@ type StartQueryOptions struct {
 	// This includes connection attempts.
 	// VOLATILE: This API is subject to change at any time.
 	MaxRetries *uint32
+
+	// RetryStrategy specifies the strategy used to decide whether, and when, to retry a failed request.
+	// This overrides the RetryStrategy set on ClusterOptions.
+	// VOLATILE: This API is subject to change at any time.
+	RetryStrategy RetryStrategy
 }
 
 // NewStartQueryOptions creates a new instance of StartQueryOptions.
@@ -146,6 +260,7 @@ func NewStartQueryOptions() *StartQueryOptions {
 		ScanConsistency:      nil,
 		Raw:                  nil,
 		MaxRetries:           nil,
+		RetryStrategy:        nil,
 	}
 }
 
@@ -199,6 +314,14 @@ func (opts *StartQueryOptions) SetMaxRetries(maxRetries uint32) *StartQueryOptio
 	return opts
 }
 
+// SetRetryStrategy sets the RetryStrategy field in StartQueryOptions.
+// VOLATILE: This API is subject to change at any time.
+func (opts *StartQueryOptions) SetRetryStrategy(retryStrategy RetryStrategy) *StartQueryOptions {
+	opts.RetryStrategy = retryStrategy
+
+	return opts
+}
+
 func mergeStartQueryOptions(opts ...*StartQueryOptions) *StartQueryOptions {
 	startOpts := &StartQueryOptions{
 		ClientContextID:      nil,
@@ -208,6 +331,7 @@ func mergeStartQueryOptions(opts ...*StartQueryOptions) *StartQueryOptions {
 		ScanConsistency:      nil,
 		Raw:                  nil,
 		MaxRetries:           nil,
+		RetryStrategy:        nil,
 	}
 
 	for _, opt := range opts {
@@ -242,6 +366,10 @@ func mergeStartQueryOptions(opts ...*StartQueryOptions) *StartQueryOptions {
 		if opt.MaxRetries != nil {
 			startOpts.MaxRetries = opt.MaxRetries
 		}
+
+		if opt.RetryStrategy != nil {
+			startOpts.RetryStrategy = opt.RetryStrategy
+		}
 	}
 
 	return startOpts
@@ -266,3 +394,216 @@ func (opts *FetchResultsOptions) SetUnmarshaler(unmarshaler Unmarshaler) *FetchR
 
 	return opts
 }
+
+// WaitOptions is the set of options available to a Wait operation on a QueryHandle.
+type WaitOptions struct {
+	// InitialInterval specifies how long to wait between the first and second status checks.
+	// Defaults to 100ms.
+	InitialInterval *time.Duration
+
+	// MaxInterval specifies the longest time to wait between status checks.
+	// Defaults to 5s.
+	MaxInterval *time.Duration
+
+	// BackoffFactor specifies the factor that the interval between status checks grows by after each check,
+	// until it reaches MaxInterval. Must be at least 1, defaults to 2.
+	BackoffFactor *float64
+
+	// Progress, if set, is called with the status of the query each time that it is found not to be complete.
+	Progress func(status *QueryStatus)
+}
+
+// NewWaitOptions creates a new instance of WaitOptions.
+func NewWaitOptions() *WaitOptions {
+	return &WaitOptions{
+		InitialInterval: nil,
+		MaxInterval:     nil,
+		BackoffFactor:   nil,
+		Progress:        nil,
+	}
+}
+
+// SetInitialInterval sets the InitialInterval field in WaitOptions.
+func (opts *WaitOptions) SetInitialInterval(interval time.Duration) *WaitOptions {
+	opts.InitialInterval = &interval
+
+	return opts
+}
+
+// SetMaxInterval sets the MaxInterval field in WaitOptions.
+func (opts *WaitOptions) SetMaxInterval(interval time.Duration) *WaitOptions {
+	opts.MaxInterval = &interval
+
+	return opts
+}
+
+// SetBackoffFactor sets the BackoffFactor field in WaitOptions.
+func (opts *WaitOptions) SetBackoffFactor(factor float64) *WaitOptions {
+	opts.BackoffFactor = &factor
+
+	return opts
+}
+
+// SetProgress sets the Progress field in WaitOptions.
+func (opts *WaitOptions) SetProgress(progress func(status *QueryStatus)) *WaitOptions {
+	opts.Progress = progress
+
+	return opts
+}
+
+func mergeWaitOptions(opts ...*WaitOptions) *WaitOptions {
+	waitOpts := &WaitOptions{
+		InitialInterval: nil,
+		MaxInterval:     nil,
+		BackoffFactor:   nil,
+		Progress:        nil,
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+
+		if opt.InitialInterval != nil {
+			waitOpts.InitialInterval = opt.InitialInterval
+		}
+
+		if opt.MaxInterval != nil {
+			waitOpts.MaxInterval = opt.MaxInterval
+		}
+
+		if opt.BackoffFactor != nil {
+			waitOpts.BackoffFactor = opt.BackoffFactor
+		}
+
+		if opt.Progress != nil {
+			waitOpts.Progress = opt.Progress
+		}
+	}
+
+	return waitOpts
+}
+
+// ExplainQueryOptions is the set of options available when explaining an Analytics query.
+// VOLATILE: This API is subject to change at any time.
+type ExplainQueryOptions struct {
+	// ClientContextID is an optional identifier for the request.
+	ClientContextID *string
+
+	// PositionalParameters sets any positional placeholder parameters for the query.
+	PositionalParameters []interface{}
+
+	// NamedParameters sets any named parameters for the query.
+	NamedParameters map[string]interface{}
+
+	// Raw provides a way to provide extra parameters in the request body.
+	Raw map[string]interface{}
+
+	// MaxRetries specifies the maximum number of retries that the request will be attempted.
+	// This includes connection attempts.
+	MaxRetries *uint32
+
+	// RetryStrategy specifies the strategy used to decide whether, and when, to retry a failed request.
+	// This overrides the RetryStrategy set on ClusterOptions.
+	RetryStrategy RetryStrategy
+}
+
+// NewExplainQueryOptions creates a new instance of ExplainQueryOptions.
+// VOLATILE: This API is subject to change at any time.
+func NewExplainQueryOptions() *ExplainQueryOptions {
+	return &ExplainQueryOptions{
+		ClientContextID:      nil,
+		PositionalParameters: nil,
+		NamedParameters:      nil,
+		Raw:                  nil,
+		MaxRetries:           nil,
+		RetryStrategy:        nil,
+	}
+}
+
+// SetClientContextID sets the ClientContextID field in ExplainQueryOptions.
+// VOLATILE: This API is subject to change at any time.
+func (opts *ExplainQueryOptions) SetClientContextID(clientContextID string) *ExplainQueryOptions {
+	opts.ClientContextID = &clientContextID
+
+	return opts
+}
+
+// SetPositionalParameters sets the PositionalParameters field in ExplainQueryOptions.
+// VOLATILE: This API is subject to change at any time.
+func (opts *ExplainQueryOptions) SetPositionalParameters(params []interface{}) *ExplainQueryOptions {
+	opts.PositionalParameters = params
+
+	return opts
+}
+
+// SetNamedParameters sets the NamedParameters field in ExplainQueryOptions.
+// VOLATILE: This API is subject to change at any time.
+func (opts *ExplainQueryOptions) SetNamedParameters(params map[string]interface{}) *ExplainQueryOptions {
+	opts.NamedParameters = params
+
+	return opts
+}
+
+// SetRaw sets the Raw field in ExplainQueryOptions.
+// VOLATILE: This API is subject to change at any time.
+func (opts *ExplainQueryOptions) SetRaw(raw map[string]interface{}) *ExplainQueryOptions {
+	opts.Raw = raw
+
+	return opts
+}
+
+// SetMaxRetries sets the MaxRetries field in ExplainQueryOptions.
+// VOLATILE: This API is subject to change at any time.
+func (opts *ExplainQueryOptions) SetMaxRetries(maxRetries uint32) *ExplainQueryOptions {
+	opts.MaxRetries = &maxRetries
+
+	return opts
+}
+
+// SetRetryStrategy sets the RetryStrategy field in ExplainQueryOptions.
+// VOLATILE: This API is subject to change at any time.
+func (opts *ExplainQueryOptions) SetRetryStrategy(retryStrategy RetryStrategy) *ExplainQueryOptions {
+	opts.RetryStrategy = retryStrategy
+
+	return opts
+}
+
+// mergeExplainQueryOptions merges opts into the QueryOptions used to execute the EXPLAIN statement,
+// which always requests the plan as JSON.
+func mergeExplainQueryOptions(opts ...*ExplainQueryOptions) *QueryOptions {
+	queryOpts := NewQueryOptions().
+		SetPlanOptions(NewQueryPlanOptions().SetFormat(QueryPlanFormatJSON))
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+
+		if opt.ClientContextID != nil {
+			queryOpts.ClientContextID = opt.ClientContextID
+		}
+
+		if len(opt.PositionalParameters) > 0 {
+			queryOpts.PositionalParameters = opt.PositionalParameters
+		}
+
+		if len(opt.NamedParameters) > 0 {
+			queryOpts.NamedParameters = opt.NamedParameters
+		}
+
+		if len(opt.Raw) > 0 {
+			queryOpts.Raw = opt.Raw
+		}
+
+		if opt.MaxRetries != nil {
+			queryOpts.MaxRetries = opt.MaxRetries
+		}
+
+		if opt.RetryStrategy != nil {
+			queryOpts.RetryStrategy = opt.RetryStrategy
+		}
+	}
+
+	return queryOpts
+}
@@ -0,0 +1,149 @@
+package cbanalytics
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// QueryPlan is a node in the tree of operators making up the plan for a query, as returned by ExplainQuery or
+// requested with QueryOptions.PlanOptions. The root node is the last operator executed, and its Inputs are the
+// operators which produce its input.
+// VOLATILE: This API is subject to change at any time.
+type QueryPlan struct {
+	// Operator is the logical operator, such as "distribute-result" or "data-scan".
+	Operator string
+
+	// OperatorID identifies the operator within the plan.
+	OperatorID string
+
+	// PhysicalOperator is the physical operator used to execute the logical operator, if known.
+	PhysicalOperator string
+
+	// ExecutionMode is how the operator is executed, such as "PARTITIONED" or "UNPARTITIONED".
+	ExecutionMode string
+
+	// Cardinality is the number of rows the optimizer estimates the operator produces, if estimated.
+	Cardinality *float64
+
+	// OpCost is the cost the optimizer estimates for the operator alone, if estimated.
+	OpCost *float64
+
+	// TotalCost is the cost the optimizer estimates for the operator and all of its inputs, if estimated.
+	TotalCost *float64
+
+	// Inputs are the operators which produce the input of this operator.
+	Inputs []*QueryPlan
+
+	// Raw is the JSON object that the node was parsed from, including any fields which are not parsed above.
+	Raw json.RawMessage
+}
+
+type jsonQueryPlanEstimates struct {
+	Cardinality *float64 `json:"cardinality"`
+	OpCost      *float64 `json:"op-cost"`
+	TotalCost   *float64 `json:"total-cost"`
+}
+
+type jsonQueryPlan struct {
+	Operator           string                  `json:"operator"`
+	OperatorID         string                  `json:"operatorId"`
+	PhysicalOperator   string                  `json:"physical-operator"`
+	ExecutionMode      string                  `json:"execution-mode"`
+	OptimizerEstimates *jsonQueryPlanEstimates `json:"optimizer-estimates"`
+	Inputs             []*QueryPlan            `json:"inputs"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler. A plan encoded as a JSON string, as some servers return it, is
+// decoded from the string.
+func (p *QueryPlan) UnmarshalJSON(data []byte) error {
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err == nil {
+		data = []byte(encoded)
+	}
+
+	var plan jsonQueryPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	*p = QueryPlan{
+		Operator:         plan.Operator,
+		OperatorID:       plan.OperatorID,
+		PhysicalOperator: plan.PhysicalOperator,
+		ExecutionMode:    plan.ExecutionMode,
+		Cardinality:      nil,
+		OpCost:           nil,
+		TotalCost:        nil,
+		Inputs:           plan.Inputs,
+		Raw:              append(json.RawMessage(nil), data...),
+	}
+
+	if plan.OptimizerEstimates != nil {
+		p.Cardinality = plan.OptimizerEstimates.Cardinality
+		p.OpCost = plan.OptimizerEstimates.OpCost
+		p.TotalCost = plan.OptimizerEstimates.TotalCost
+	}
+
+	return nil
+}
+
+// ParseQueryPlan parses a plan returned in JSON format, such as QueryPlans.OptimizedLogicalPlan when the plan was
+// requested with QueryPlanFormatJSON.
+// VOLATILE: This API is subject to change at any time.
+func ParseQueryPlan(data []byte) (*QueryPlan, error) {
+	var plan QueryPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, invalidArgumentError{
+			ArgumentName: "data",
+			Reason:       "not a query plan in JSON format: " + err.Error(),
+		}
+	}
+
+	return &plan, nil
+}
+
+// QueryPlans holds the plans returned alongside the results of a query, when requested with
+// QueryOptions.PlanOptions. Plans requested with QueryPlanFormatJSON can be parsed with ParseQueryPlan, plans
+// requested with QueryPlanFormatString are JSON strings.
+// VOLATILE: This API is subject to change at any time.
+type QueryPlans struct {
+	// LogicalPlan is the logical plan before optimization, if requested.
+	LogicalPlan json.RawMessage
+
+	// OptimizedLogicalPlan is the optimized logical plan, if requested.
+	OptimizedLogicalPlan json.RawMessage
+}
+
+// ExplainQuery returns the plan that the server would use to execute the query statement, without executing it.
+// When ExplainQuery is called with no context.Context, or a context.Context with no Deadline, then
+// the Cluster level QueryTimeout will be applied.
+// VOLATILE: This API is subject to change at any time.
+func (c *Cluster) ExplainQuery(ctx context.Context, statement string,
+	opts ...*ExplainQueryOptions) (*QueryPlan, error) {
+	return explainQuery(ctx, c, statement, opts...)
+}
+
+// ExplainQuery returns the plan that the server would use to execute the query statement, without executing it,
+// tying the query context to this Scope.
+// When ExplainQuery is called with no context.Context, or a context.Context with no Deadline, then
+// the Cluster level QueryTimeout will be applied.
+// VOLATILE: This API is subject to change at any time.
+func (s *Scope) ExplainQuery(ctx context.Context, statement string,
+	opts ...*ExplainQueryOptions) (*QueryPlan, error) {
+	return explainQuery(ctx, s, statement, opts...)
+}
+
+func explainQuery(ctx context.Context, querier Querier, statement string,
+	opts ...*ExplainQueryOptions) (*QueryPlan, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	row, err := queryOneRow(ctx, querier, "EXPLAIN "+strings.TrimSpace(statement), mergeExplainQueryOptions(opts...))
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseQueryPlan(row.rowBytes)
+}
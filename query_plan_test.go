@@ -0,0 +1,130 @@
+package cbanalytics
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/couchbase/gocbanalytics/analyticstest"
+)
+
+const testExplainPlan = `{"operator":"distribute-result","operatorId":"1.3","physical-operator":"DISTRIBUTE_RESULT",` +
+	`"execution-mode":"PARTITIONED","optimizer-estimates":{"cardinality":100,"op-cost":0,"total-cost":250.5},` +
+	`"inputs":[{"operator":"data-scan","operatorId":"1.1","physical-operator":"DATASOURCE_SCAN",` +
+	`"execution-mode":"PARTITIONED","optimizer-estimates":{"cardinality":100,"op-cost":250.5,"total-cost":250.5}}]}`
+
+// lastPayload returns the payload of the last query request received by a fake server.
+func lastPayload(t *testing.T, srv *analyticstest.Server) map[string]interface{} {
+	t.Helper()
+
+	requests := srv.Requests()
+	require.NotEmpty(t, requests)
+
+	return requests[len(requests)-1].Payload
+}
+
+func assertTestExplainPlan(t *testing.T, plan *QueryPlan) {
+	t.Helper()
+
+	assert.Equal(t, "distribute-result", plan.Operator)
+	assert.Equal(t, "1.3", plan.OperatorID)
+	assert.Equal(t, "DISTRIBUTE_RESULT", plan.PhysicalOperator)
+	assert.Equal(t, "PARTITIONED", plan.ExecutionMode)
+	require.NotNil(t, plan.Cardinality)
+	assert.InDelta(t, 100, *plan.Cardinality, 0)
+	require.NotNil(t, plan.TotalCost)
+	assert.InDelta(t, 250.5, *plan.TotalCost, 0)
+
+	require.Len(t, plan.Inputs, 1)
+	assert.Equal(t, "data-scan", plan.Inputs[0].Operator)
+	require.NotNil(t, plan.Inputs[0].OpCost)
+	assert.InDelta(t, 250.5, *plan.Inputs[0].OpCost, 0)
+	assert.Empty(t, plan.Inputs[0].Inputs)
+}
+
+func TestExplainQuery(t *testing.T) {
+	srv := newFakeServer(t, analyticstest.Rows(json.RawMessage(testExplainPlan)))
+	cluster := connectTestCluster(t, srv)
+
+	for _, explainer := range []interface {
+		ExplainQuery(context.Context, string, ...*ExplainQueryOptions) (*QueryPlan, error)
+	}{cluster, cluster.Database("travel").Scope("inventory")} {
+		plan, err := explainer.ExplainQuery(context.Background(), "SELECT * FROM t WHERE id = $1",
+			NewExplainQueryOptions().SetPositionalParameters([]interface{}{1}))
+		require.NoError(t, err)
+
+		assertTestExplainPlan(t, plan)
+		assert.JSONEq(t, testExplainPlan, string(plan.Raw))
+
+		payload := lastPayload(t, srv)
+		assert.Equal(t, "EXPLAIN SELECT * FROM t WHERE id = $1", payload["statement"])
+		assert.Equal(t, "JSON", payload["plan-format"])
+		assert.Equal(t, []interface{}{float64(1)}, payload["args"])
+	}
+}
+
+func TestExplainQueryStringEncodedPlan(t *testing.T) {
+	// The plan is sent as a JSON string.
+	cluster := newTestCluster(t, analyticstest.Rows(testExplainPlan))
+
+	plan, err := cluster.ExplainQuery(context.Background(), "SELECT 1")
+	require.NoError(t, err)
+
+	assertTestExplainPlan(t, plan)
+}
+
+func TestExplainQueryNotAPlan(t *testing.T) {
+	cluster := newTestCluster(t, analyticstest.Rows([]int{1, 2}))
+
+	_, err := cluster.ExplainQuery(context.Background(), "SELECT 1")
+	require.ErrorIs(t, err, ErrInvalidArgument)
+}
+
+func TestQueryPlanOptions(t *testing.T) {
+	srv := newFakeServer(t, analyticstest.Rows(1).SetPlan(json.RawMessage(testExplainPlan)))
+	cluster := connectTestCluster(t, srv)
+
+	res, err := cluster.ExecuteQuery(context.Background(), "SELECT VALUE 1", NewQueryOptions().
+		SetPlanOptions(NewQueryPlanOptions().SetLogicalPlan(true).SetOptimizedLogicalPlan(true)))
+	require.NoError(t, err)
+
+	_, meta, err := BufferQueryResult[int](res)
+	require.NoError(t, err)
+
+	payload := lastPayload(t, srv)
+	assert.Nil(t, payload["plan-format"])
+	assert.Equal(t, true, payload["logical-plan"])
+	assert.Equal(t, true, payload["optimized-logical-plan"])
+
+	require.NotNil(t, meta.Plans)
+
+	plan, err := ParseQueryPlan(meta.Plans.OptimizedLogicalPlan)
+	require.NoError(t, err)
+	assertTestExplainPlan(t, plan)
+
+	plan, err = ParseQueryPlan(meta.Plans.LogicalPlan)
+	require.NoError(t, err)
+	assertTestExplainPlan(t, plan)
+}
+
+func TestQueryPlanOptionsFormat(t *testing.T) {
+	srv := newFakeServer(t, analyticstest.Rows(1))
+	cluster := connectTestCluster(t, srv)
+
+	res, err := cluster.ExecuteQuery(context.Background(), "SELECT VALUE 1", NewQueryOptions().
+		SetPlanOptions(NewQueryPlanOptions().SetFormat(QueryPlanFormatString)))
+	require.NoError(t, err)
+
+	_, meta, err := BufferQueryResult[int](res)
+	require.NoError(t, err)
+
+	assert.Equal(t, "STRING", lastPayload(t, srv)["plan-format"])
+	assert.Nil(t, meta.Plans)
+
+	_, err = cluster.ExecuteQuery(context.Background(), "SELECT VALUE 1", NewQueryOptions().
+		SetPlanOptions(NewQueryPlanOptions().SetFormat(QueryPlanFormat(99))))
+	require.ErrorIs(t, err, ErrInvalidArgument)
+}
@@ -1,6 +1,8 @@
 package cbanalytics
 
 import (
+	"encoding/json"
+	"iter"
 	"time"
 )
 
@@ -24,6 +26,15 @@ type QueryMetadata struct {
 	RequestID string
 	Metrics   QueryMetrics
 	Warnings  []QueryWarning
+
+	// QueueTime is how long the query waited before being sent, because the maximum number of queries were
+	// already in flight. See ClusterOptions.ConcurrencyLimitOptions.
+	QueueTime time.Duration
+
+	// Plans holds the query plans returned by the server, or nil if none were requested.
+	// See QueryOptions.PlanOptions.
+	// VOLATILE: This API is subject to change at any time.
+	Plans *QueryPlans
 }
 
 // QueryResult allows access to the results of a query.
@@ -31,10 +42,15 @@ type QueryResult struct {
 	reader analyticsRowReader
 
 	unmarshaler Unmarshaler
+	closed      bool
 }
 
 // NextRow returns the next row in the result set, or nil if there are no more rows.
 func (r *QueryResult) NextRow() *QueryResultRow {
+	if r.closed {
+		return nil
+	}
+
 	rowBytes := r.reader.NextRow()
 	if rowBytes == nil {
 		return nil
@@ -48,7 +64,7 @@ func (r *QueryResult) NextRow() *QueryResultRow {
 
 // Err returns any errors that have occurred on the stream.
 func (r *QueryResult) Err() error {
-	if r.reader == nil {
+	if r.reader == nil || r.closed {
 		return ErrClosed
 	}
 
@@ -60,18 +76,113 @@ func (r *QueryResult) Err() error {
 	return nil
 }
 
+// Close releases the resources held by the result, discarding any rows which have not yet been read and
+// releasing the underlying HTTP response. After Close, NextRow returns nil and Err returns ErrClosed.
+// If CancelOnClose was set in QueryOptions and the rows had not all been read, the query is also cancelled
+// on the server in the background. Calling Close more than once has no effect.
+func (r *QueryResult) Close() error {
+	if r.closed {
+		return nil
+	}
+
+	r.closed = true
+
+	err := r.reader.Close()
+	if err != nil {
+		return err // nolint:wrapcheck
+	}
+
+	return nil
+}
+
+// Signature returns the signature describing the shape of the rows, as sent by the server ahead of the rows,
+// or nil if the server did not send one. This is available before the rows have been read.
+func (r *QueryResult) Signature() json.RawMessage {
+	return r.reader.Signature()
+}
+
 // MetaData returns any meta-data that was available from this query.  Note that
 // the meta-data will only be available once the object has been closed (either
 // implicitly or explicitly).
 func (r *QueryResult) MetaData() (*QueryMetadata, error) {
 	meta, err := r.reader.MetaData()
 	if err != nil {
+		if r.closed {
+			return nil, ErrClosed
+		}
+
 		return nil, err // nolint:wrapcheck
 	}
 
 	return meta, nil
 }
 
+// Rows returns an iterator over the rows in the result set, for use with range. Once the rows have all been
+// read, any error that occurred while streaming them, or with the meta-data that follows them, is yielded as
+// the final element along with a nil row. If the loop is exited early then the result is closed.
+func (r *QueryResult) Rows() iter.Seq2[*QueryResultRow, error] {
+	return func(yield func(*QueryResultRow, error) bool) {
+		for row := r.NextRow(); row != nil; row = r.NextRow() {
+			if !yield(row, nil) {
+				// The caller no longer wants the rows and has no way to receive an error from closing.
+				_ = r.Close()
+
+				return
+			}
+		}
+
+		if err := r.Err(); err != nil {
+			yield(nil, err)
+
+			return
+		}
+
+		if _, err := r.MetaData(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// RowsAs returns an iterator over the rows in the result set, decoding each row into a T using the
+// Unmarshaler of the result, for use with range. Once the rows have all been read, any error that occurred
+// while streaming them, or with the meta-data that follows them, is yielded as the final element. If the loop
+// is exited early, or a row cannot be decoded, then the result is closed.
+func RowsAs[T any](result *QueryResult) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		if result == nil {
+			yield(zero, invalidArgumentError{
+				ArgumentName: "result",
+				Reason:       "result cannot be nil",
+			})
+
+			return
+		}
+
+		// Returning from the loop closes the result.
+		for row, err := range result.Rows() {
+			if err != nil {
+				yield(zero, err)
+
+				return
+			}
+
+			var contentAs T
+
+			if err := row.ContentAs(&contentAs); err != nil {
+				yield(zero, err)
+
+				return
+			}
+
+			if !yield(contentAs, nil) {
+				return
+			}
+		}
+	}
+}
+
 // QueryResultRow encapsulates a single row of a query result.
 type QueryResultRow struct {
 	rowBytes []byte
@@ -124,6 +235,7 @@ func BufferQueryResult[T any](result *QueryResult) ([]T, *QueryMetadata, error)
 type analyticsRowReader interface {
 	NextRow() []byte
 	MetaData() (*QueryMetadata, error)
+	Signature() json.RawMessage
 	Close() error
 	Err() error
 }
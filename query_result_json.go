@@ -1,6 +1,7 @@
 package cbanalytics
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -21,6 +22,11 @@ type jsonAnalyticsWarning struct {
 	Message string `json:"msg"`
 }
 
+type jsonAnalyticsPlans struct {
+	LogicalPlan          json.RawMessage `json:"logicalPlan,omitempty"`
+	OptimizedLogicalPlan json.RawMessage `json:"optimizedLogicalPlan,omitempty"`
+}
+
 type jsonAnalyticsResponse struct {
 	RequestID       string                 `json:"requestID"`
 	ClientContextID string                 `json:"clientContextID"`
@@ -29,6 +35,7 @@ type jsonAnalyticsResponse struct {
 	Metrics         jsonAnalyticsMetrics   `json:"metrics"`
 	Signature       interface{}            `json:"signature"`
 	Handle          string                 `json:"handle,omitempty"`
+	Plans           *jsonAnalyticsPlans    `json:"plans,omitempty"`
 }
 
 func (meta *QueryMetadata) fromData(data jsonAnalyticsResponse) {
@@ -49,6 +56,13 @@ func (meta *QueryMetadata) fromData(data jsonAnalyticsResponse) {
 	meta.RequestID = data.RequestID
 	meta.Metrics = metrics
 	meta.Warnings = warnings
+
+	if data.Plans != nil {
+		meta.Plans = &QueryPlans{
+			LogicalPlan:          data.Plans.LogicalPlan,
+			OptimizedLogicalPlan: data.Plans.OptimizedLogicalPlan,
+		}
+	}
 }
 
 func (metrics *QueryMetrics) fromData(data jsonAnalyticsMetrics) {
@@ -61,6 +75,27 @@ func (metrics *QueryMetrics) fromData(data jsonAnalyticsMetrics) {
 	metrics.ProcessedObjects = data.ProcessedObjects
 }
 
+func (status *QueryStatus) fromData(data jsonHandleStatusResponse) {
+	status.status = data.Status
+	status.metrics = string(data.Metrics)
+
+	// A createdAt which is not an RFC 3339 timestamp is treated as absent, rather than failing the whole status.
+	var createdAtStr string
+	if err := json.Unmarshal(data.CreatedAt, &createdAtStr); err == nil {
+		createdAt, err := time.Parse(time.RFC3339Nano, createdAtStr)
+		if err == nil {
+			status.createdAt = createdAt
+		}
+	}
+
+	if len(data.Metrics) > 0 {
+		var metrics jsonAnalyticsMetrics
+		if err := json.Unmarshal(data.Metrics, &metrics); err == nil {
+			status.parsedMetrics.fromData(metrics)
+		}
+	}
+}
+
 func (warning *QueryWarning) fromData(data jsonAnalyticsWarning) {
 	warning.Code = data.Code
 	warning.Message = data.Message
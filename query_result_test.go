@@ -0,0 +1,340 @@
+package cbanalytics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/couchbase/gocbanalytics/analyticstest"
+	"github.com/couchbase/gocbanalytics/internal/httpqueryclient"
+	"github.com/couchbase/gocbanalytics/internal/logging"
+)
+
+func newTestHTTPQueryClient(t *testing.T, addr string, meter Meter) *httpQueryClient {
+	t.Helper()
+
+	host, portStr, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+
+	port, err := strconv.Atoi(portStr)
+	require.NoError(t, err)
+
+	client := httpqueryclient.NewClient("http", []httpqueryclient.Endpoint{{Host: host, Port: port}},
+		httpqueryclient.ClientConfig{
+			TLSConfig:      nil,
+			Logger:         logging.NewDefaultLogger(logging.LogTrace, 0),
+			ConnectTimeout: 5 * time.Second,
+			RetryStrategy:  httpqueryclient.NewExponentialBackoffRetryStrategy(time.Millisecond, time.Millisecond, 2),
+		})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	return newHTTPQueryClient(httpQueryClientConfig{
+		Credentials:               newCredentialStore(NewBasicAuthCredential("user", "pass")),
+		Client:                    client,
+		Namespace:                 nil,
+		Logger:                    NewNoopLogger(),
+		Tracer:                    NewNoopTracer(),
+		Meter:                     meter,
+		DefaultServerQueryTimeout: time.Minute,
+		DefaultUnmarshaler:        NewJSONUnmarshaler(),
+		DefaultMaxRetries:         5,
+	})
+}
+
+// newStreamingServer returns a server which sends a handful of rows for a query and then stalls until the
+// client disconnects. Any cancel requests received are sent to cancels.
+func newStreamingServer(t *testing.T, cancels chan<- string, disconnected chan<- struct{}) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			body, _ := io.ReadAll(r.Body)
+			form, _ := url.ParseQuery(string(body))
+			cancels <- form.Get("request_id")
+
+			w.WriteHeader(http.StatusOK)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"requestID":"req-1","signature":{"*":"*"},"results":[`))
+
+		for i := 0; i < 5; i++ {
+			if i > 0 {
+				_, _ = w.Write([]byte(","))
+			}
+
+			_, _ = fmt.Fprintf(w, `{"n":%d}`, i)
+		}
+
+		w.(http.Flusher).Flush()
+
+		<-r.Context().Done()
+		close(disconnected)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestQueryResultCloseAbandonsStream(t *testing.T) {
+	cancels := make(chan string, 1)
+	disconnected := make(chan struct{})
+
+	srv := newStreamingServer(t, cancels, disconnected)
+	client := newTestHTTPQueryClient(t, srv.Listener.Addr().String(), NewNoopMeter())
+
+	res, err := client.Query(context.Background(), "SELECT 1", NewQueryOptions())
+	require.NoError(t, err)
+
+	require.NotNil(t, res.NextRow())
+	require.NoError(t, res.Close())
+
+	assert.Nil(t, res.NextRow())
+	require.ErrorIs(t, res.Err(), ErrClosed)
+
+	_, err = res.MetaData()
+	require.ErrorIs(t, err, ErrClosed)
+
+	// Closing again has no effect.
+	require.NoError(t, res.Close())
+
+	select {
+	case <-disconnected:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "server did not observe the stream being closed")
+	}
+
+	select {
+	case requestID := <-cancels:
+		require.Failf(t, "unexpected cancel", "cancel sent for %s", requestID)
+	default:
+	}
+}
+
+func TestQueryResultCloseCancelsOnServer(t *testing.T) {
+	cancels := make(chan string, 1)
+	disconnected := make(chan struct{})
+
+	srv := newStreamingServer(t, cancels, disconnected)
+	client := newTestHTTPQueryClient(t, srv.Listener.Addr().String(), NewNoopMeter())
+
+	res, err := client.Query(context.Background(), "SELECT 1", NewQueryOptions().SetCancelOnClose(true))
+	require.NoError(t, err)
+
+	require.NotNil(t, res.NextRow())
+	require.NoError(t, res.Close())
+
+	select {
+	case requestID := <-cancels:
+		assert.Equal(t, "req-1", requestID)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "server did not receive a cancel request")
+	}
+}
+
+func TestQueryResultCloseCancelsOnServerOnce(t *testing.T) {
+	cancels := make(chan string, 2)
+	disconnected := make(chan struct{})
+
+	srv := newStreamingServer(t, cancels, disconnected)
+	client := newTestHTTPQueryClient(t, srv.Listener.Addr().String(), NewNoopMeter())
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	res, err := client.Query(ctx, "SELECT 1", NewQueryOptions().SetCancelOnClose(true))
+	require.NoError(t, err)
+
+	require.NotNil(t, res.NextRow())
+
+	// The result is abandoned both because its context is cancelled and because it is closed early.
+	cancel()
+	require.NoError(t, res.Close())
+
+	// Closing the client waits for the background cancel to be sent.
+	require.NoError(t, client.client.Close())
+
+	require.Len(t, cancels, 1)
+	assert.Equal(t, "req-1", <-cancels)
+}
+
+func TestQueryResultCloseAfterCompletion(t *testing.T) {
+	var cancelled atomic.Bool
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			cancelled.Store(true)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"requestID":"req-1","results":[1,2],"status":"success","metrics":{"resultCount":2}}`))
+	}))
+	defer srv.Close()
+
+	client := newTestHTTPQueryClient(t, srv.Listener.Addr().String(), NewNoopMeter())
+
+	res, err := client.Query(context.Background(), "SELECT 1", NewQueryOptions().SetCancelOnClose(true))
+	require.NoError(t, err)
+
+	for res.NextRow() != nil {
+	}
+
+	require.NoError(t, res.Err())
+	require.NoError(t, res.Close())
+	require.ErrorIs(t, res.Err(), ErrClosed)
+
+	meta, err := res.MetaData()
+	require.NoError(t, err)
+	assert.Equal(t, "req-1", meta.RequestID)
+	assert.False(t, cancelled.Load())
+}
+
+// newFakeServer starts a fake server which responds to every statement with each of responses in turn.
+func newFakeServer(t *testing.T, responses ...*analyticstest.Response) *analyticstest.Server {
+	t.Helper()
+
+	srv := analyticstest.NewServer()
+	t.Cleanup(srv.Close)
+
+	srv.SetDefault(responses...)
+
+	return srv
+}
+
+// fakeServerAddr returns the host:port that a fake server is listening on.
+func fakeServerAddr(t *testing.T, srv *analyticstest.Server) string {
+	t.Helper()
+
+	u, err := url.Parse(srv.URL())
+	require.NoError(t, err)
+
+	return u.Host
+}
+
+func TestQueryResultRows(t *testing.T) {
+	srv := newFakeServer(t, analyticstest.Rows(1, 2, 3))
+	client := newTestHTTPQueryClient(t, fakeServerAddr(t, srv), NewNoopMeter())
+
+	res, err := client.Query(context.Background(), "SELECT 1", NewQueryOptions())
+	require.NoError(t, err)
+
+	var values []int
+
+	for row, err := range res.Rows() {
+		require.NoError(t, err)
+
+		var value int
+		require.NoError(t, row.ContentAs(&value))
+
+		values = append(values, value)
+	}
+
+	assert.Equal(t, []int{1, 2, 3}, values)
+}
+
+func TestQueryResultRowsAs(t *testing.T) {
+	srv := newFakeServer(t, analyticstest.Rows(map[string]interface{}{"n": 1}, map[string]interface{}{"n": 2}))
+	client := newTestHTTPQueryClient(t, fakeServerAddr(t, srv), NewNoopMeter())
+
+	res, err := client.Query(context.Background(), "SELECT 1", NewQueryOptions())
+	require.NoError(t, err)
+
+	type row struct {
+		N int `json:"n"`
+	}
+
+	var rows []row
+
+	for r, err := range RowsAs[row](res) {
+		require.NoError(t, err)
+
+		rows = append(rows, r)
+	}
+
+	assert.Equal(t, []row{{N: 1}, {N: 2}}, rows)
+}
+
+func TestQueryResultRowsYieldsStreamError(t *testing.T) {
+	srv := newFakeServer(t, analyticstest.Rows(1, 2).
+		SetErrors(analyticstest.Error{Code: 23007, Message: "job failed", Retriable: false}))
+	client := newTestHTTPQueryClient(t, fakeServerAddr(t, srv), NewNoopMeter())
+
+	res, err := client.Query(context.Background(), "SELECT 1", NewQueryOptions())
+	require.NoError(t, err)
+
+	var (
+		values  []int
+		lastErr error
+	)
+
+	for value, err := range RowsAs[int](res) {
+		if err != nil {
+			lastErr = err
+
+			continue
+		}
+
+		values = append(values, value)
+	}
+
+	assert.Equal(t, []int{1, 2}, values)
+	require.ErrorIs(t, lastErr, ErrQuery)
+}
+
+func TestQueryResultRowsAsDecodeError(t *testing.T) {
+	srv := newFakeServer(t, analyticstest.Rows(1, "a", 3))
+	client := newTestHTTPQueryClient(t, fakeServerAddr(t, srv), NewNoopMeter())
+
+	res, err := client.Query(context.Background(), "SELECT 1", NewQueryOptions())
+	require.NoError(t, err)
+
+	var errs int
+
+	for _, err := range RowsAs[int](res) {
+		if err != nil {
+			errs++
+		}
+	}
+
+	assert.Equal(t, 1, errs)
+	require.ErrorIs(t, res.Err(), ErrClosed)
+}
+
+func TestQueryResultRowsBreakClosesStream(t *testing.T) {
+	cancels := make(chan string, 1)
+	disconnected := make(chan struct{})
+
+	srv := newStreamingServer(t, cancels, disconnected)
+	client := newTestHTTPQueryClient(t, srv.Listener.Addr().String(), NewNoopMeter())
+
+	res, err := client.Query(context.Background(), "SELECT 1", NewQueryOptions())
+	require.NoError(t, err)
+
+	for _, err := range RowsAs[map[string]int](res) {
+		require.NoError(t, err)
+
+		break
+	}
+
+	require.ErrorIs(t, res.Err(), ErrClosed)
+
+	select {
+	case <-disconnected:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "server did not observe the stream being closed")
+	}
+}
@@ -0,0 +1,113 @@
+package cbanalytics
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+)
+
+// QueryOne executes a query which is expected to return exactly one row, decoding the row into a T.
+// ErrNoRows is returned if the query returns no rows, and ErrTooManyRows if it returns more than one.
+// The stream is closed once the row has been read, so the remaining rows are not read from the server.
+func QueryOne[T any](ctx context.Context, querier Querier, statement string, opts ...*QueryOptions) (T, error) {
+	var zero T
+
+	row, err := queryOneRow(ctx, querier, statement, opts...)
+	if err != nil {
+		return zero, err
+	}
+
+	var contentAs T
+
+	if err := row.ContentAs(&contentAs); err != nil {
+		return zero, err
+	}
+
+	return contentAs, nil
+}
+
+// QueryScalar executes a query which is expected to return exactly one row holding a single value, such as
+// SELECT COUNT(*), decoding the value into a T. If the row is an object and T is not a map or struct then the
+// object must have exactly one field, whose value is decoded, otherwise the row itself is decoded, as for
+// SELECT VALUE queries.
+// ErrNoRows is returned if the query returns no rows, ErrTooManyRows if it returns more than one, and
+// ErrNotScalar if the field to decode cannot be chosen because the object does not have exactly one field.
+func QueryScalar[T any](ctx context.Context, querier Querier, statement string,
+	opts ...*QueryOptions) (T, error) {
+	var zero T
+
+	row, err := queryOneRow(ctx, querier, statement, opts...)
+	if err != nil {
+		return zero, err
+	}
+
+	var fields map[string]json.RawMessage
+	if !decodesObjects[T]() && json.Unmarshal(row.rowBytes, &fields) == nil {
+		if len(fields) != 1 {
+			return zero, ErrNotScalar
+		}
+
+		for _, value := range fields {
+			row.rowBytes = value
+		}
+	}
+
+	var contentAs T
+
+	if err := row.ContentAs(&contentAs); err != nil {
+		return zero, err
+	}
+
+	return contentAs, nil
+}
+
+// decodesObjects returns whether T, or what it points to, is a map or struct, which an object row is decoded
+// into as a whole rather than being unwrapped.
+func decodesObjects[T any]() bool {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	return typ.Kind() == reflect.Map || typ.Kind() == reflect.Struct
+}
+
+// queryOneRow executes a query and returns its only row, closing the result.
+func queryOneRow(ctx context.Context, querier Querier, statement string,
+	opts ...*QueryOptions) (*QueryResultRow, error) {
+	if querier == nil {
+		return nil, invalidArgumentError{
+			ArgumentName: "querier",
+			Reason:       "querier cannot be nil",
+		}
+	}
+
+	result, err := querier.ExecuteQuery(ctx, statement, opts...)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	defer func() {
+		// Any rows that are left are not wanted, so there is nothing to be done about an error here.
+		_ = result.Close()
+	}()
+
+	row := result.NextRow()
+	if row == nil {
+		if err := result.Err(); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrNoRows
+	}
+
+	if result.NextRow() != nil {
+		return nil, ErrTooManyRows
+	}
+
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+
+	return row, nil
+}
@@ -0,0 +1,122 @@
+package cbanalytics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/couchbase/gocbanalytics/analyticstest"
+)
+
+// newTestCluster returns a cluster connected to a fake server which responds to every statement with response.
+func newTestCluster(t *testing.T, response *analyticstest.Response) *Cluster {
+	t.Helper()
+
+	return connectTestCluster(t, newFakeServer(t, response))
+}
+
+// connectTestCluster returns a cluster connected to a fake server.
+func connectTestCluster(t *testing.T, srv *analyticstest.Server) *Cluster {
+	t.Helper()
+
+	cluster, err := NewCluster(srv.URL(), NewBasicAuthCredential("user", "pass"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = cluster.Close()
+	})
+
+	return cluster
+}
+
+func TestQueryOne(t *testing.T) {
+	cluster := newTestCluster(t, analyticstest.Rows(map[string]interface{}{"name": "a", "id": 1}))
+
+	type row struct {
+		Name string `json:"name"`
+		ID   int    `json:"id"`
+	}
+
+	for _, querier := range []Querier{cluster, cluster.Database("travel").Scope("inventory")} {
+		actual, err := QueryOne[row](context.Background(), querier, "SELECT name, id FROM t WHERE id = 1")
+		require.NoError(t, err)
+		assert.Equal(t, row{Name: "a", ID: 1}, actual)
+	}
+}
+
+func TestQueryOneNoRows(t *testing.T) {
+	cluster := newTestCluster(t, analyticstest.Rows())
+
+	_, err := QueryOne[int](context.Background(), cluster, "SELECT VALUE 1 FROM t WHERE false")
+	require.ErrorIs(t, err, ErrNoRows)
+}
+
+func TestQueryOneTooManyRows(t *testing.T) {
+	cluster := newTestCluster(t, analyticstest.Rows(1, 2, 3))
+
+	_, err := QueryOne[int](context.Background(), cluster, "SELECT VALUE 1 FROM t")
+	require.ErrorIs(t, err, ErrTooManyRows)
+}
+
+func TestQueryOneStreamError(t *testing.T) {
+	cluster := newTestCluster(t, analyticstest.Rows(1).
+		SetErrors(analyticstest.Error{Code: 23007, Message: "job failed", Retriable: false}))
+
+	_, err := QueryOne[int](context.Background(), cluster, "SELECT VALUE 1")
+	require.ErrorIs(t, err, ErrQuery)
+}
+
+func TestQueryScalar(t *testing.T) {
+	cluster := newTestCluster(t, analyticstest.Rows(map[string]interface{}{"$1": 42}))
+
+	count, err := QueryScalar[int](context.Background(), cluster, "SELECT COUNT(*) FROM t")
+	require.NoError(t, err)
+	assert.Equal(t, 42, count)
+}
+
+func TestQueryScalarValue(t *testing.T) {
+	cluster := newTestCluster(t, analyticstest.Rows("a"))
+
+	name, err := QueryScalar[string](context.Background(), cluster, "SELECT VALUE name FROM t")
+	require.NoError(t, err)
+	assert.Equal(t, "a", name)
+}
+
+func TestQueryScalarMultipleFields(t *testing.T) {
+	cluster := newTestCluster(t, analyticstest.Rows(map[string]interface{}{"a": 1, "b": 2}))
+
+	_, err := QueryScalar[int](context.Background(), cluster, "SELECT a, b FROM t")
+	require.ErrorIs(t, err, ErrNotScalar)
+	require.NotErrorIs(t, err, ErrInvalidArgument)
+}
+
+func TestQueryScalarObject(t *testing.T) {
+	cluster := newTestCluster(t, analyticstest.Rows(map[string]interface{}{"name": "a"}))
+
+	object, err := QueryScalar[map[string]string](context.Background(), cluster, `SELECT VALUE {"name": name} FROM t`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"name": "a"}, object)
+
+	type row struct {
+		Name string `json:"name"`
+	}
+
+	actual, err := QueryScalar[*row](context.Background(), cluster, `SELECT VALUE {"name": name} FROM t`)
+	require.NoError(t, err)
+	assert.Equal(t, &row{Name: "a"}, actual)
+}
+
+func TestQueryScalarStructMultipleFields(t *testing.T) {
+	cluster := newTestCluster(t, analyticstest.Rows(map[string]interface{}{"name": "a", "id": 1}))
+
+	type row struct {
+		Name string `json:"name"`
+		ID   int    `json:"id"`
+	}
+
+	actual, err := QueryScalar[row](context.Background(), cluster, `SELECT VALUE {"name": name, "id": id} FROM t`)
+	require.NoError(t, err)
+	assert.Equal(t, row{Name: "a", ID: 1}, actual)
+}
@@ -0,0 +1,135 @@
+package cbanalytics
+
+import (
+	"time"
+
+	"github.com/couchbase/gocbanalytics/internal/httpqueryclient"
+)
+
+// RetryRequest contains the details of a failed request attempt which a RetryStrategy is asked to decide on.
+// A RetryStrategy is only consulted for failures which the SDK considers to be retriable.
+type RetryRequest struct {
+	// Attempt is the number of retries that have already been made for this request, starting at 0.
+	Attempt uint32
+
+	// Errors contains any errors returned by the server in the response body.
+	Errors []ErrorDesc
+
+	// TransportError is the error that occurred when the request could not be sent, or no response was received.
+	TransportError error
+
+	// HTTPStatusCode is the HTTP status code of the response, or 0 if no response was received.
+	HTTPStatusCode int
+
+	// Deadline is the point in time at which the operation will time out, or the zero time if there is no deadline.
+	Deadline time.Time
+}
+
+// RetryStrategy decides whether a failed request should be retried, and how long to wait before doing so.
+// Regardless of the decision made by the strategy, a request will never be retried more than MaxRetries times,
+// or beyond the deadline of the operation.
+// VOLATILE: This API is subject to change at any time.
+type RetryStrategy interface {
+	// RetryAfter returns the duration to wait before retrying the request, and whether the request should
+	// be retried at all.
+	RetryAfter(request RetryRequest) (time.Duration, bool)
+}
+
+// ExponentialBackoffRetryStrategy is a RetryStrategy which retries with an exponentially increasing,
+// jittered, backoff. This is the default RetryStrategy.
+type ExponentialBackoffRetryStrategy struct {
+	strategy *httpqueryclient.BackoffRetryStrategy
+}
+
+// NewExponentialBackoffRetryStrategy creates a new ExponentialBackoffRetryStrategy.
+// The backoff for each attempt is a random duration up to minBackoff * backoffFactor^attempt, bounded
+// by minBackoff and maxBackoff.
+func NewExponentialBackoffRetryStrategy(minBackoff, maxBackoff time.Duration,
+	backoffFactor float64) *ExponentialBackoffRetryStrategy {
+	return &ExponentialBackoffRetryStrategy{
+		strategy: httpqueryclient.NewExponentialBackoffRetryStrategy(minBackoff, maxBackoff, backoffFactor),
+	}
+}
+
+// RetryAfter returns the backoff for the given request attempt.
+func (s *ExponentialBackoffRetryStrategy) RetryAfter(request RetryRequest) (time.Duration, bool) {
+	return s.strategy.RetryAfter(&httpqueryclient.RetryRequest{
+		Attempt:        request.Attempt,
+		Errors:         nil,
+		TransportError: request.TransportError,
+		HTTPStatusCode: request.HTTPStatusCode,
+		Deadline:       request.Deadline,
+	})
+}
+
+// FixedDelayRetryStrategy is a RetryStrategy which always waits for the same duration before retrying.
+type FixedDelayRetryStrategy struct {
+	delay time.Duration
+}
+
+// NewFixedDelayRetryStrategy creates a new FixedDelayRetryStrategy.
+func NewFixedDelayRetryStrategy(delay time.Duration) *FixedDelayRetryStrategy {
+	return &FixedDelayRetryStrategy{
+		delay: delay,
+	}
+}
+
+// RetryAfter returns the fixed delay.
+func (s *FixedDelayRetryStrategy) RetryAfter(_ RetryRequest) (time.Duration, bool) {
+	return s.delay, true
+}
+
+// NoRetryStrategy is a RetryStrategy which never retries.
+type NoRetryStrategy struct{}
+
+// NewNoRetryStrategy creates a new NoRetryStrategy.
+func NewNoRetryStrategy() *NoRetryStrategy {
+	return &NoRetryStrategy{}
+}
+
+// RetryAfter always declines to retry.
+func (s *NoRetryStrategy) RetryAfter(_ RetryRequest) (time.Duration, bool) {
+	return 0, false
+}
+
+func newDefaultRetryStrategy() RetryStrategy {
+	return NewExponentialBackoffRetryStrategy(100*time.Millisecond, 1*time.Minute, 2)
+}
+
+// retryStrategyWrapper adapts a RetryStrategy to the interface expected by the http client.
+type retryStrategyWrapper struct {
+	strategy RetryStrategy
+}
+
+func newRetryStrategyWrapper(strategy RetryStrategy) httpqueryclient.RetryStrategy {
+	if strategy == nil {
+		return nil
+	}
+
+	return &retryStrategyWrapper{
+		strategy: strategy,
+	}
+}
+
+func (w *retryStrategyWrapper) RetryAfter(req *httpqueryclient.RetryRequest) (time.Duration, bool) {
+	var descs []ErrorDesc
+
+	if len(req.Errors) > 0 {
+		descs = make([]ErrorDesc, len(req.Errors))
+		for i, desc := range req.Errors {
+			descs[i] = ErrorDesc{
+				Code:      desc.Code,
+				Message:   desc.Message,
+				Retriable: desc.Retry,
+			}
+		}
+	}
+
+	return w.strategy.RetryAfter(RetryRequest{
+		Attempt:        req.Attempt,
+		Errors:         descs,
+		TransportError: req.TransportError,
+		HTTPStatusCode: req.HTTPStatusCode,
+		Deadline:       req.Deadline,
+	})
+}
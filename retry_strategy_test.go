@@ -0,0 +1,78 @@
+package cbanalytics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/couchbase/gocbanalytics/internal/httpqueryclient"
+)
+
+func TestExponentialBackoffRetryStrategyBounds(t *testing.T) {
+	strategy := NewExponentialBackoffRetryStrategy(10*time.Millisecond, 50*time.Millisecond, 2)
+
+	for attempt := uint32(0); attempt < 10; attempt++ {
+		backoff, retry := strategy.RetryAfter(RetryRequest{Attempt: attempt}) //nolint:exhaustruct
+		require.True(t, retry)
+		assert.GreaterOrEqual(t, backoff, 10*time.Millisecond)
+		assert.LessOrEqual(t, backoff, 50*time.Millisecond)
+	}
+}
+
+func TestFixedDelayRetryStrategy(t *testing.T) {
+	strategy := NewFixedDelayRetryStrategy(250 * time.Millisecond)
+
+	backoff, retry := strategy.RetryAfter(RetryRequest{Attempt: 5}) //nolint:exhaustruct
+	assert.True(t, retry)
+	assert.Equal(t, 250*time.Millisecond, backoff)
+}
+
+func TestNoRetryStrategy(t *testing.T) {
+	_, retry := NewNoRetryStrategy().RetryAfter(RetryRequest{}) //nolint:exhaustruct
+	assert.False(t, retry)
+}
+
+type recordingRetryStrategy struct {
+	request RetryRequest
+}
+
+func (s *recordingRetryStrategy) RetryAfter(request RetryRequest) (time.Duration, bool) {
+	s.request = request
+
+	return time.Second, true
+}
+
+func TestRetryStrategyWrapperTranslatesRequest(t *testing.T) {
+	strategy := &recordingRetryStrategy{} //nolint:exhaustruct
+	deadline := time.Now().Add(time.Minute)
+	transportErr := errors.New("connection reset") // nolint: err113
+
+	backoff, retry := newRetryStrategyWrapper(strategy).RetryAfter(&httpqueryclient.RetryRequest{
+		Attempt: 3,
+		Errors: []httpqueryclient.ErrorDesc{
+			{Code: 23000, Message: "service unavailable", Retry: true},
+		},
+		TransportError: transportErr,
+		HTTPStatusCode: 503,
+		Deadline:       deadline,
+	})
+	require.True(t, retry)
+	assert.Equal(t, time.Second, backoff)
+
+	assert.Equal(t, RetryRequest{
+		Attempt: 3,
+		Errors: []ErrorDesc{
+			{Code: 23000, Message: "service unavailable", Retriable: true},
+		},
+		TransportError: transportErr,
+		HTTPStatusCode: 503,
+		Deadline:       deadline,
+	}, strategy.request)
+}
+
+func TestRetryStrategyWrapperNil(t *testing.T) {
+	assert.Nil(t, newRetryStrategyWrapper(nil))
+}
@@ -76,6 +76,37 @@ func TestStartQueryGoldenPath(t *testing.T) {
 	})
 }
 
+func TestStartQueryWait(t *testing.T) {
+	cluster, err := cbanalytics.NewCluster(TestOpts.OriginalConnStr, cbanalytics.NewBasicAuthCredential(TestOpts.Username, TestOpts.Password), DefaultOptions())
+	require.NoError(t, err)
+
+	defer func(cluster *cbanalytics.Cluster) {
+		err := cluster.Close()
+		assert.NoError(t, err)
+	}(cluster)
+
+	StartQueryAgainst(t, []DeferredQueryable{cluster, cluster.Database(TestOpts.Database).Scope(TestOpts.Scope)}, func(tt *testing.T, queryable DeferredQueryable) {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+
+		handle, err := queryable.StartQuery(ctx, "FROM RANGE(0, 9) AS i SELECT RAW i")
+		require.NoError(tt, err)
+		require.NotNil(tt, handle)
+
+		resultHandle, err := handle.Wait(ctx, cbanalytics.NewWaitOptions().SetInitialInterval(50*time.Millisecond))
+		require.NoError(tt, err)
+
+		res, err := resultHandle.FetchResults(ctx)
+		require.NoError(tt, err)
+
+		actualRows := CollectRows[int](tt, res)
+		require.Len(tt, actualRows, 10)
+
+		err = res.Err()
+		require.NoError(tt, err)
+	})
+}
+
 func TestStartQueryCancel(t *testing.T) {
 	cluster, err := cbanalytics.NewCluster(TestOpts.OriginalConnStr, cbanalytics.NewBasicAuthCredential(TestOpts.Username, TestOpts.Password), DefaultOptions())
 	require.NoError(t, err)
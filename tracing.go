@@ -0,0 +1,115 @@
+package cbanalytics
+
+import (
+	"context"
+
+	"github.com/couchbase/gocbanalytics/internal/httpqueryclient"
+)
+
+// Names of the spans created by the SDK for each operation.
+const (
+	SpanNameExecuteQuery   = "execute_query"
+	SpanNameStartQuery     = "start_query"
+	SpanNameFetchStatus    = "fetch_status"
+	SpanNameFetchResults   = "fetch_results"
+	SpanNameCancel         = "cancel"
+	SpanNameDiscardResults = "discard_results"
+
+	// SpanNameDispatchToServer is the name of the child span created for each HTTP attempt made by an operation.
+	SpanNameDispatchToServer = httpqueryclient.SpanNameAttempt
+)
+
+// Attribute keys set on the spans created by the SDK.
+const (
+	SpanAttributeSystem          = httpqueryclient.SpanAttributeSystem
+	SpanAttributeService         = httpqueryclient.SpanAttributeService
+	SpanAttributeOperation       = httpqueryclient.SpanAttributeOperation
+	SpanAttributeStatement       = httpqueryclient.SpanAttributeStatement
+	SpanAttributeRetries         = httpqueryclient.SpanAttributeRetries
+	SpanAttributeServerAddress   = httpqueryclient.SpanAttributeServerAddress
+	SpanAttributeServerPort      = httpqueryclient.SpanAttributeServerPort
+	SpanAttributeStatusCode      = httpqueryclient.SpanAttributeStatusCode
+	SpanAttributeClientContextID = httpqueryclient.SpanAttributeClientContextID
+	SpanAttributeRequestID       = httpqueryclient.SpanAttributeRequestID
+)
+
+// RequestSpanContext is the parent of a span.
+// For spans created for an operation this is the context.Context passed to the operation, for child spans
+// this is the value returned by Context on the parent RequestSpan.
+type RequestSpanContext interface{}
+
+// RequestTracer describes the tracing abstraction in the SDK.
+// VOLATILE: This API is subject to change at any time.
+type RequestTracer interface {
+	// RequestSpan creates a new span with the given parent.
+	RequestSpan(parentContext RequestSpanContext, operationName string) RequestSpan
+}
+
+// RequestSpan is the interface for spans that are created by a RequestTracer.
+// VOLATILE: This API is subject to change at any time.
+type RequestSpan interface {
+	// End completes the span.
+	End()
+
+	// Context returns the RequestSpanContext to use when creating children of this span.
+	Context() RequestSpanContext
+
+	// SetAttribute sets an attribute on the span.
+	SetAttribute(key string, value interface{})
+}
+
+// NoopTracer is a RequestTracer which does nothing.
+// This is equivalent to specifying Tracer on ClusterOptions as nil.
+type NoopTracer struct{}
+
+// NewNoopTracer creates a new NoopTracer instance.
+func NewNoopTracer() *NoopTracer {
+	return &NoopTracer{}
+}
+
+// RequestSpan creates a span which does nothing.
+func (t *NoopTracer) RequestSpan(_ RequestSpanContext, _ string) RequestSpan {
+	return noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (s noopSpan) End() {}
+
+func (s noopSpan) Context() RequestSpanContext {
+	return nil
+}
+
+func (s noopSpan) SetAttribute(_ string, _ interface{}) {}
+
+// tracerParentSpan allows the http client to create children of an operation span.
+type tracerParentSpan struct {
+	tracer RequestTracer
+	span   RequestSpan
+}
+
+func (p *tracerParentSpan) StartChild(name string) httpqueryclient.Span {
+	span := p.tracer.RequestSpan(p.span.Context(), name)
+	span.SetAttribute(SpanAttributeSystem, "couchbase")
+	span.SetAttribute(SpanAttributeService, "analytics")
+
+	return span
+}
+
+// startOperationSpan creates the span for an operation, and returns a context which causes the http client
+// to create children of that span for each attempt.
+func startOperationSpan(ctx context.Context, tracer RequestTracer, operationName string) (context.Context, RequestSpan) {
+	if tracer == nil {
+		return ctx, noopSpan{}
+	}
+
+	span := tracer.RequestSpan(ctx, operationName)
+	span.SetAttribute(SpanAttributeSystem, "couchbase")
+	span.SetAttribute(SpanAttributeService, "analytics")
+	span.SetAttribute(SpanAttributeOperation, operationName)
+
+	return httpqueryclient.ContextWithParentSpan(ctx, &tracerParentSpan{
+		tracer: tracer,
+		span:   span,
+	}), span
+}
@@ -0,0 +1,83 @@
+package cbanalytics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSpan struct {
+	name       string
+	parent     RequestSpanContext
+	attributes map[string]interface{}
+	ended      bool
+}
+
+func (s *testSpan) End() {
+	s.ended = true
+}
+
+func (s *testSpan) Context() RequestSpanContext {
+	return s
+}
+
+func (s *testSpan) SetAttribute(key string, value interface{}) {
+	s.attributes[key] = value
+}
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) RequestSpan(parentContext RequestSpanContext, operationName string) RequestSpan {
+	span := &testSpan{
+		name:       operationName,
+		parent:     parentContext,
+		attributes: make(map[string]interface{}),
+		ended:      false,
+	}
+	t.spans = append(t.spans, span)
+
+	return span
+}
+
+func TestStartOperationSpan(t *testing.T) {
+	tracer := &testTracer{}
+
+	ctx := context.Background()
+
+	opCtx, span := startOperationSpan(ctx, tracer, SpanNameExecuteQuery)
+	require.NotNil(t, opCtx)
+
+	require.Len(t, tracer.spans, 1)
+	opSpan := tracer.spans[0]
+
+	assert.Equal(t, SpanNameExecuteQuery, opSpan.name)
+	assert.Equal(t, ctx, opSpan.parent)
+	assert.Equal(t, "couchbase", opSpan.attributes[SpanAttributeSystem])
+	assert.Equal(t, "analytics", opSpan.attributes[SpanAttributeService])
+	assert.Equal(t, SpanNameExecuteQuery, opSpan.attributes[SpanAttributeOperation])
+
+	parent := &tracerParentSpan{
+		tracer: tracer,
+		span:   span,
+	}
+
+	child := parent.StartChild(SpanNameDispatchToServer)
+	child.End()
+
+	require.Len(t, tracer.spans, 2)
+	assert.Equal(t, SpanNameDispatchToServer, tracer.spans[1].name)
+	assert.Equal(t, opSpan, tracer.spans[1].parent)
+	assert.True(t, tracer.spans[1].ended)
+}
+
+func TestStartOperationSpanNilTracer(t *testing.T) {
+	ctx := context.Background()
+
+	opCtx, span := startOperationSpan(ctx, nil, SpanNameExecuteQuery)
+	assert.Equal(t, ctx, opCtx)
+	assert.Nil(t, span.Context())
+}